@@ -192,6 +192,11 @@ var acmCmd = &cobra.Command{
 					"Status": status,
 				}
 
+				if len(auths) > 1 {
+					labelsOrder = append(labelsOrder, "AWS Session")
+					certInfo["AWS Session"] = fmtAWSSession(auth, " ")
+				}
+
 				if getLogLevelFromVerbosity() >= log.DebugLevel {
 					labelsOrder = append(labelsOrder, []string{"Created", "Expire In", "Validation"}...)
 					certInfo["Created"] = created.String()
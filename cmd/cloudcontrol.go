@@ -263,10 +263,11 @@ surf aws search  -q 'my-prod'  -t vpc -t eks::cluster -a 'ClusterName=my-cluster
 					log.WithError(err).Fatalf("failed parsing resource properties")
 				}
 				jsonOutput["matches"] = append(jsonOutput["matches"].([]map[string]any), map[string]any{
-					"account":  r.Auth.EffectiveProfile + "-" + r.Auth.EffectiveRegion,
-					"type":     r.ResourceType.String(),
-					"id":       rid,
-					"resource": resourceObj,
+					"account":    r.Auth.EffectiveProfile + "-" + r.Auth.EffectiveRegion,
+					"account_id": r.Auth.GetAccountID(),
+					"type":       r.ResourceType.String(),
+					"id":         rid,
+					"resource":   resourceObj,
 				})
 			}
 			for _, e := range allErrs {
@@ -284,7 +285,7 @@ surf aws search  -q 'my-prod'  -t vpc -t eks::cluster -a 'ClusterName=my-cluster
 		for _, r := range allResults {
 			rid, _ := r.Resource.GetIdentifier()
 			info := map[string]string{
-				"Account":  r.Auth.EffectiveProfile + "-" + r.Auth.EffectiveRegion,
+				"Account":  fmtAWSSession(r.Auth, "-"),
 				"Type":     r.ResourceType.String(),
 				"ID":       rid,
 				"Resource": r.Resource.GetRawProperties(),
@@ -480,6 +481,7 @@ var cloudcontrolCmdList = &cobra.Command{
 				}
 				jsonOut = append(jsonOut, map[string]any{
 					"account":    r.Auth.EffectiveProfile + "-" + r.Auth.EffectiveRegion,
+					"account_id": r.Auth.GetAccountID(),
 					"type":       r.ResourceType.String(),
 					"id":         rid,
 					"properties": properties,
@@ -504,7 +506,7 @@ var cloudcontrolCmdList = &cobra.Command{
 				log.WithError(err).Fatalf("failed getting resource identifier")
 			}
 			tableInfo := map[string]string{
-				"Account":  r.Auth.EffectiveProfile + "-" + r.Auth.EffectiveRegion,
+				"Account":  fmtAWSSession(r.Auth, "-"),
 				"Type":     r.ResourceType.String(),
 				"ID":       rid,
 				"Resource": r.Resource.GetRawProperties(),
@@ -45,6 +45,7 @@ const (
 	VaultLdap       ls.Namespace = "vault-ldap"
 	ElasticSearchNS ls.Namespace = "elastic-auth"
 	LogzSearchNS    ls.Namespace = "logz-auth"
+	ConsulNS        ls.Namespace = "consul-auth"
 )
 
 // configCmd represents the config command
@@ -54,7 +55,7 @@ var configCmd = &cobra.Command{
 	Long:  `Use the config command to configure everything from Auth to Parameters and platforms.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if *clearAllStorage {
-			clearAll([]ls.Namespace{VaultLdap, ElasticSearchNS, LogzSearchNS})
+			clearAll([]ls.Namespace{VaultLdap, ElasticSearchNS, LogzSearchNS, ConsulNS})
 			log.Info("all storage cleaned")
 		} else if *listAll {
 			if err := listAllKeychainDetails(); err != nil {
@@ -82,6 +83,8 @@ var optsToHandlers = map[string]func() error{
 	"ElasticSearch: clear storage":                       func() error { return clearNamespace(ElasticSearchNS) },
 	"Logz.io: store locally token":                       func() error { return setLocalstoreToken(LogzSearchNS) },
 	"Logz.io: clear storage":                             func() error { return clearNamespace(LogzSearchNS) },
+	"Consul: store locally ACL token":                    func() error { return setLocalstoreToken(ConsulNS) },
+	"Consul: clear storage":                              func() error { return clearNamespace(ConsulNS) },
 	"List all stored keychain details":                   listAllKeychainDetails,
 	"Opt-Out from latest version check at github.com":    getEnvConfigOutput(EnvVersionCheckOptout, "type 'false' to opt-out"),
 	"S3: set default bucket name to start search from":   getEnvConfigOutput(EnvKeyS3DefaultBucket, "enter default bucket name (regex pattern)"),
@@ -383,6 +386,7 @@ func newStoreManager() ls.StoreManager[ls.Store] {
 		VaultLdap:       {unameKey, pwdKey},
 		ElasticSearchNS: {tokenKey, unameKey, pwdKey},
 		LogzSearchNS:    {tokenKey},
+		ConsulNS:        {tokenKey},
 	})
 	return sm
 }
@@ -16,9 +16,14 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"time"
 
+	api "github.com/hashicorp/consul/api"
 	consul "github.com/isan-rivkin/surf/lib/consul"
 	common "github.com/isan-rivkin/surf/lib/search"
 	search "github.com/isan-rivkin/surf/lib/search/consulsearch"
@@ -28,56 +33,128 @@ import (
 )
 
 var (
-	consulDatacenter *string
-	consulPrefix     *string
-	consulQuery      *string
-	consulAddr       *string
-	consulWebOutput  *bool
-	consulFilterKV   *bool
+	consulDatacenters    *[]string
+	consulAllDatacenters *bool
+	consulPrefix         *string
+	consulQuery          *string
+	consulAddr           *string
+	consulWebOutput      *bool
+	consulFilterKV       *bool
+	consulFilterServices *bool
+	consulFilterTags     *bool
+	consulFilterSvcMeta  *bool
+	consulFilterNodes    *bool
+	consulFilterNodeMeta *bool
+	consulFilterChecks   *bool
+	consulToken          *string
+	consulTokenFile      *string
+	consulNamespace      *string
+	consulPartition      *string
+	consulAllNamespaces  *bool
+	consulCACert         *string
+	consulCAPath         *string
+	consulClientCert     *string
+	consulClientKey      *string
+	consulTLSServerName  *string
+	consulTLSSkipVerify  *bool
+	consulUpdateCreds    *bool
+	consulExportFile     *string
 )
 
 // consulCmd represents the consul command
 var consulCmd = &cobra.Command{
 	Use:   "consul",
-	Short: "pattern matching against keys in Hasicorp Consul",
+	Short: "pattern matching against keys and catalog in Hasicorp Consul",
 	Long: `
-Pattern matching against keys in Hasicorp Consul
+Pattern matching against keys and the service catalog in Hasicorp Consul
 
 	- Consul address taken from  CONSUL_HTTP_ADDR or via --address
+	- ACL token taken from --token, --token-file, CONSUL_HTTP_TOKEN(_FILE) or the OS keychain ('surf config')
+	- TLS and enterprise settings respect CONSUL_CACERT, CONSUL_CLIENT_CERT, CONSUL_NAMESPACE, CONSUL_PARTITION etc
 
 	$surf consul -q "user=\w+\.\w+"
 	$surf consul -q "AWS_SECRET_ACCESS_KEY"
 	$surf consul -q ldap -p ops -d op-us-west-2 --output-url=false
+	$surf consul -q ldap -d op-us-west-2 -d op-eu-west-1
+	$surf consul -q ldap --all-datacenters
+
+=== catalog search (services, tags, meta, nodes, health checks) ===
+
+	$surf consul -q canary --filter-tags
+	$surf consul -q "api-.*" --filter-services --filter-nodes
+	$surf consul -q "10\.0\.1\." --filter-nodes --filter-node-meta
+	$surf consul -q "connection refused" --filter-checks
+
+=== consul enterprise namespaces and partitions ===
+
+	$surf consul -q ldap --namespace team-a --partition prod
+	$surf consul -q ldap --all-namespaces
+
+=== export matched keys (consul kv export format) and import them to another datacenter ===
+
+	$surf consul -q "db-.*" -p services --export backup.json
+	$surf consul import -f backup.json -d dc2 --rewrite '^services/=services-dr/' --dry-run
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if !*consulFilterKV {
-			log.Fatal("for now only key-value search is supported for consul")
+		if *consulQuery == "" {
+			log.Fatal("must specify a query --query (see --help)")
+		}
+		targets := getConsulSearchTargets(cmd)
+		if len(targets) == 0 {
+			log.Fatal("nothing to search, use at least one of the --filter-* flags (see --help)")
+		}
+		if *consulExportFile != "" && !hasConsulTarget(targets, search.KVTarget) {
+			log.Fatal("--export writes KV pairs only, the catalog --filter-* flags would always export 0 keys (add --filter-kv)")
 		}
 		tui := buildTUI()
 
 		client := runConsulDefaultAuth()
-		consulUiBaseAddr, uiAddrErr := client.GetConsulUIBaseAddr()
 		consulAddress := client.GetConsulAddr()
 
-		if *consulDatacenter == "" {
+		datacenters := *consulDatacenters
+		if *consulAllDatacenters {
+			dcs, err := client.ListDatacenters()
+			if err != nil {
+				log.WithError(err).Fatal("failed listing datacenters")
+			}
+			datacenters = dcs
+		} else if len(datacenters) == 0 {
 			dc, err := client.GetCurrentDatacenter()
 			if err != nil {
 				log.WithError(err).Fatal("failed getting current data center info from agent")
 			}
-			*consulDatacenter = dc
+			datacenters = []string{dc}
+		}
+
+		namespaces := []string{client.GetNamespace()}
+		if *consulAllNamespaces {
+			nss, err := client.ListNamespaces()
+			if err != nil {
+				log.WithError(err).Fatal("failed listing namespaces (namespaces require Consul Enterprise)")
+			}
+			namespaces = nss
+		}
+
+		if *consulExportFile != "" && (len(datacenters) > 1 || len(namespaces) > 1) {
+			log.Fatal("export supports a single datacenter and namespace, keys would collide")
 		}
 
 		log.WithFields(log.Fields{
 			"address":      consulAddress,
 			"base_path":    *consulPrefix,
 			"query":        *consulQuery,
-			"dc":           *consulDatacenter,
+			"dc":           datacenters,
+			"namespaces":   namespaces,
+			"partition":    client.GetPartition(),
 			"outputWebURL": *consulWebOutput,
 		}).Info("starting search")
 
 		tui.GetLoader().Start("searching consul", "", "green")
 
-		input := search.NewSearchInput(*consulQuery, *consulPrefix)
+		input := search.NewSearchInput(*consulQuery, *consulPrefix).
+			WithDatacenters(datacenters).
+			WithNamespaces(namespaces).
+			WithTargets(targets)
 
 		m := common.NewDefaultRegexMatcher()
 		s := search.NewSearcher[consul.Client, common.Matcher](client, m)
@@ -89,17 +166,40 @@ Pattern matching against keys in Hasicorp Consul
 			log.WithError(err).Fatal("error while searching for keys")
 		}
 
+		if *consulExportFile != "" {
+			exportConsulMatches(output, *consulExportFile, tui)
+			return
+		}
+
+		uiBaseAddrs, uiAddrErr := getConsulUIBaseAddrs(client, datacenters, namespaces)
+		multiDC := len(datacenters) > 1
+		multiNS := len(namespaces) > 1
+
 		if *consulWebOutput && uiAddrErr == nil {
-			for _, key := range output.Matches {
-				webUrl := consul.GenerateKVWebURL(consulUiBaseAddr, key)
-				fmt.Println(printer.FmtURL(webUrl))
+			for _, match := range output.Matches {
+				// nodes are not namespaced, their UI is the same in every namespace
+				ns := match.Namespace
+				if ns == "" {
+					ns = namespaces[0]
+				}
+				webUrl := printer.FmtURL(generateConsulMatchWebURL(uiBaseAddrs[consulScopeKey(match.Datacenter, ns)], match))
+				fmt.Println(fmtConsulMatch(match, webUrl, multiDC, multiNS))
 			}
 
-			labelsOrder := []string{"Total", "Address", "Datacenter"}
+			labelsOrder := []string{"Matches #", "Address", "Datacenter"}
 			summary := map[string]string{
 				"Matches #":  fmt.Sprintf("%d", len(output.Matches)),
 				"Address":    consulAddress,
-				"Datacenter": *consulDatacenter,
+				"Datacenter": strings.Join(datacenters, ", "),
+			}
+
+			if multiNS || client.GetNamespace() != "" {
+				summary["Namespace"] = strings.Join(namespaces, ", ")
+				labelsOrder = append(labelsOrder, "Namespace")
+			}
+			if client.GetPartition() != "" {
+				summary["Partition"] = client.GetPartition()
+				labelsOrder = append(labelsOrder, "Partition")
 			}
 
 			if *consulPrefix != "" {
@@ -113,8 +213,8 @@ Pattern matching against keys in Hasicorp Consul
 
 			tui.GetTable().PrintInfoBox(summary, labelsOrder, false)
 		} else {
-			for i, key := range output.Matches {
-				fmt.Printf("%d. %s\n", i, key)
+			for i, match := range output.Matches {
+				fmt.Printf("%d. %s\n", i, fmtConsulMatch(match, match.Name(), multiDC, multiNS))
 			}
 			if uiAddrErr != nil {
 				log.WithError(uiAddrErr).Error("Not Displaying Link to UI, failed building address UI")
@@ -123,27 +223,368 @@ Pattern matching against keys in Hasicorp Consul
 	},
 }
 
+var consulWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "watch for added, modified and deleted keys matching a pattern",
+	Long: `
+Watch keys matching a pattern under a prefix using consul blocking queries, runs until interrupted.
+
+	$surf consul watch -q "feature-flags/.*" -p config
+	$surf consul watch -q "\.json$" -p migrations --output-url=false
+	$surf consul watch -q "feature-flags/.*" -p config -d dc2
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if *consulQuery == "" {
+			log.Fatal("must specify a query --query (see --help)")
+		}
+		// blocking queries watch a single datacenter and namespace
+		if *consulAllDatacenters || len(*consulDatacenters) > 1 {
+			log.Fatal("watch supports a single datacenter, specify at most one --datacenter (-d)")
+		}
+		if *consulAllNamespaces {
+			log.Fatal("watch supports a single namespace, use --namespace instead of --all-namespaces")
+		}
+		client := runConsulDefaultAuth()
+		uiBaseAddr, uiAddrErr := client.GetConsulUIBaseAddr()
+		if uiAddrErr != nil {
+			log.WithError(uiAddrErr).Error("Not Displaying Link to UI, failed building address UI")
+		}
+
+		log.WithFields(log.Fields{
+			"address":   client.GetConsulAddr(),
+			"base_path": *consulPrefix,
+			"query":     *consulQuery,
+		}).Info("watching for changes, ctrl+c to stop")
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		input := search.NewSearchInput(*consulQuery, *consulPrefix)
+		m := common.NewDefaultRegexMatcher()
+		w := search.NewWatcher[consul.Client, common.Matcher](client, m)
+
+		events := make(chan *search.WatchEvent)
+		errs := make(chan error, 1)
+		go func() {
+			errs <- w.Watch(ctx, input, events)
+		}()
+
+		for {
+			select {
+			case e := <-events:
+				out := e.Key
+				if *consulWebOutput && uiAddrErr == nil && e.Type != search.KeyDeleted {
+					out = printer.FmtURL(consul.GenerateKVWebURL(uiBaseAddr, e.Key))
+				}
+				fmt.Printf("%s [%s] %s\n", e.Time.Format(time.RFC3339), strings.ToUpper(string(e.Type)), out)
+			case err := <-errs:
+				if err != nil {
+					log.WithError(err).Fatal("failed watching keys")
+				}
+				return
+			}
+		}
+	},
+}
+
+var consulImportCmd = &cobra.Command{
+	Use:   "import -f <file>",
+	Short: "import keys exported with --export or 'consul kv export'",
+	Long: `
+Import keys from a 'consul kv export' compatible JSON file, existing keys with the same value are skipped.
+
+	$surf consul import -f backup.json -d dc2 --dry-run
+	$surf consul import -f backup.json -d dc2 --rewrite '^prod/(.*)=staging/$1'
+	$surf consul import -f backup.json -d dc2 -q "db-.*"
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		if file == "" {
+			log.Fatal("must specify a file to import --file (see --help)")
+		}
+		// writes must never fallback to the agent default datacenter by mistake
+		if *consulAllDatacenters || len(*consulDatacenters) != 1 {
+			log.Fatal("import writes to a single datacenter, specify exactly one --datacenter (-d)")
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		rewriteRule, _ := cmd.Flags().GetString("rewrite")
+
+		rewrite, err := consul.ParseKeyRewrite(rewriteRule)
+		if err != nil {
+			log.WithError(err).Fatal("invalid rewrite rule")
+		}
+
+		entries, err := consul.ReadExportFile(file)
+		if err != nil {
+			log.WithError(err).Fatal("failed reading import file")
+		}
+
+		// -q is an optional filter on the source keys
+		if *consulQuery != "" {
+			m := common.NewDefaultRegexMatcher()
+			var filtered []*consul.ExportedKV
+			for _, e := range entries {
+				match, err := m.IsMatch(*consulQuery, e.Key)
+				if err != nil {
+					log.WithError(err).Fatal("failed matching query")
+				}
+				if match {
+					filtered = append(filtered, e)
+				}
+			}
+			entries = filtered
+		}
+
+		client := runConsulDefaultAuth()
+		dc, err := client.GetCurrentDatacenter()
+		if err != nil {
+			log.WithError(err).Fatal("failed getting the target datacenter info from agent")
+		}
+
+		tui := buildTUI()
+		tui.GetLoader().Start("comparing keys", "", "green")
+		plan, err := consul.PlanImport(client, entries, rewrite)
+		tui.GetLoader().Stop()
+		if err != nil {
+			log.WithError(err).Fatal("failed planning import")
+		}
+
+		counts := map[consul.ImportAction]int{}
+		for _, item := range plan {
+			counts[item.Action]++
+			if item.Action == consul.ImportUnchanged && getLogLevelFromVerbosity() < log.DebugLevel {
+				continue
+			}
+			key := item.Key
+			if item.Key != item.SourceKey {
+				key = fmt.Sprintf("%s -> %s", item.SourceKey, item.Key)
+			}
+			fmt.Printf("[%s] %s\n", strings.ToUpper(string(item.Action)), key)
+		}
+
+		if !dryRun {
+			if err := consul.ApplyImport(client, plan); err != nil {
+				log.WithError(err).Fatal("failed importing keys")
+			}
+		}
+
+		labelsOrder := []string{"Mode", "Datacenter", "Create", "Update", "Unchanged"}
+		mode := "import"
+		if dryRun {
+			mode = "dry-run (nothing written)"
+		}
+		tui.GetTable().PrintInfoBox(map[string]string{
+			"Mode":       mode,
+			"Datacenter": dc,
+			"Create":     fmt.Sprintf("%d", counts[consul.ImportCreate]),
+			"Update":     fmt.Sprintf("%d", counts[consul.ImportUpdate]),
+			"Unchanged":  fmt.Sprintf("%d", counts[consul.ImportUnchanged]),
+		}, labelsOrder, false)
+	},
+}
+
+func exportConsulMatches(output *search.Output, file string, tui printer.TuiController[printer.Loader, printer.Table]) {
+	pairs := api.KVPairs{}
+	for _, match := range output.Matches {
+		if match.Pair != nil {
+			pairs = append(pairs, match.Pair)
+		}
+	}
+	if err := consul.WriteExportFile(file, consul.NewExportedKVs(pairs)); err != nil {
+		log.WithError(err).Fatal("failed exporting keys")
+	}
+	tui.GetTable().PrintInfoBox(map[string]string{
+		"Exported #": fmt.Sprintf("%d", len(pairs)),
+		"File":       file,
+	}, []string{"Exported #", "File"}, false)
+}
+
+func hasConsulTarget(targets []search.SearchTarget, t search.SearchTarget) bool {
+	for _, target := range targets {
+		if target == t {
+			return true
+		}
+	}
+	return false
+}
+
+// getConsulSearchTargets resolves the --filter-* flags, KV is the default unless only catalog filters are set
+func getConsulSearchTargets(cmd *cobra.Command) []search.SearchTarget {
+	catalogFilters := map[search.SearchTarget]*bool{
+		search.ServiceNameTarget: consulFilterServices,
+		search.ServiceTagsTarget: consulFilterTags,
+		search.ServiceMetaTarget: consulFilterSvcMeta,
+		search.NodeTarget:        consulFilterNodes,
+		search.NodeMetaTarget:    consulFilterNodeMeta,
+		search.HealthCheckTarget: consulFilterChecks,
+	}
+	var targets []search.SearchTarget
+	for t, enabled := range catalogFilters {
+		if *enabled {
+			targets = append(targets, t)
+		}
+	}
+	withKV := *consulFilterKV
+	if len(targets) > 0 && !cmd.Flags().Changed("filter-kv") {
+		withKV = false
+	}
+	if withKV {
+		targets = append(targets, search.KVTarget)
+	}
+	return targets
+}
+
+func generateConsulMatchWebURL(uiBaseAddr string, match *search.Match) string {
+	switch match.Target {
+	case search.KVTarget:
+		return consul.GenerateKVWebURL(uiBaseAddr, match.Key)
+	case search.ServiceNameTarget, search.ServiceTagsTarget, search.ServiceMetaTarget:
+		return consul.GenerateServiceWebURL(uiBaseAddr, match.Service)
+	default:
+		return consul.GenerateNodeWebURL(uiBaseAddr, match.Node)
+	}
+}
+
+func fmtConsulMatch(match *search.Match, display string, multiDC, multiNS bool) string {
+	out := display
+	if match.Target == search.HealthCheckTarget {
+		out = fmt.Sprintf("%s %s=%s service=%s node=%s %s", match.Target, match.Field, match.Value, match.Service, match.Node, display)
+	} else if match.Target != search.KVTarget {
+		out = fmt.Sprintf("%s %s=%s %s", match.Target, match.Field, match.Value, display)
+	}
+	if multiNS && match.Namespace != "" {
+		out = fmt.Sprintf("[ns:%s] %s", match.Namespace, out)
+	}
+	if multiDC {
+		out = fmt.Sprintf("[%s] %s", match.Datacenter, out)
+	}
+	return out
+}
+
+func consulScopeKey(dc, ns string) string {
+	return dc + "/" + ns
+}
+
+// getConsulUIBaseAddrs returns the UI base address of every datacenter and namespace searched
+func getConsulUIBaseAddrs(client consul.Client, datacenters, namespaces []string) (map[string]string, error) {
+	addrs := map[string]string{}
+	for _, dc := range datacenters {
+		dcClient, err := client.WithDatacenter(dc)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range namespaces {
+			nsClient, err := dcClient.WithNamespace(ns)
+			if err != nil {
+				return nil, err
+			}
+			addr, err := nsClient.GetConsulUIBaseAddr()
+			if err != nil {
+				return nil, err
+			}
+			addrs[consulScopeKey(dc, ns)] = addr
+		}
+	}
+	return addrs, nil
+}
+
 func runConsulDefaultAuth() consul.Client {
 	if *consulAddr == "" {
 		*consulAddr = os.Getenv("CONSUL_HTTP_ADDR")
 	}
 
-	client, err := consul.NewClient(*consulAddr, *consulDatacenter)
+	// with multiple datacenters the base client stays on the agent default and the searcher fans out
+	datacenter := ""
+	if len(*consulDatacenters) == 1 {
+		datacenter = (*consulDatacenters)[0]
+	}
+
+	conf := &consul.ClientConfig{
+		Address:    *consulAddr,
+		Datacenter: datacenter,
+		Token:      getConsulToken(),
+		TokenFile:  *consulTokenFile,
+		Namespace:  *consulNamespace,
+		Partition:  *consulPartition,
+		TLS: consul.TLSConfig{
+			CAFile:             *consulCACert,
+			CAPath:             *consulCAPath,
+			CertFile:           *consulClientCert,
+			KeyFile:            *consulClientKey,
+			ServerName:         *consulTLSServerName,
+			InsecureSkipVerify: *consulTLSSkipVerify,
+		},
+	}
+
+	client, err := consul.NewClientFromConfig(conf)
 	if err != nil {
 		log.WithError(err).Fatal("failed creating consul client")
 	}
 	return client
 }
 
+// getConsulToken hirearchy --token flag > --token-file / CONSUL_HTTP_TOKEN(_FILE) > OS keychain
+func getConsulToken() string {
+	if *consulToken != "" {
+		return *consulToken
+	}
+	if *consulTokenFile != "" || os.Getenv("CONSUL_HTTP_TOKEN") != "" || os.Getenv("CONSUL_HTTP_TOKEN_FILE") != "" {
+		// resolved by the consul client, the token file defaults to CONSUL_HTTP_TOKEN_FILE
+		return ""
+	}
+	hasToken, _, err := checkIsTokenOrUserAuthStored(ConsulNS)
+	if err != nil {
+		log.WithError(err).Debug("failed accessing OS keychain for consul token")
+		return ""
+	}
+	if !hasToken && !*consulUpdateCreds {
+		return ""
+	}
+	updateLocalCredentials = consulUpdateCreds
+	token, err := getAccessTokenValue(ConsulNS, consulToken, map[string]bool{"ldap": true})
+	if err != nil {
+		log.WithError(err).Error("failed reading consul token from OS keychain")
+		return ""
+	}
+	return token
+}
+
 func init() {
 	rootCmd.AddCommand(consulCmd)
+	consulCmd.AddCommand(consulWatchCmd)
+	consulCmd.AddCommand(consulImportCmd)
+	consulImportCmd.Flags().StringP("file", "f", "", "consul kv export compatible JSON file to import")
+	consulImportCmd.Flags().Bool("dry-run", false, "only print the diff of what would be created and updated")
+	consulImportCmd.Flags().String("rewrite", "", "rewrite keys before import with a regex 'pattern=replacement' (usage: --rewrite '^prod/=staging/')")
 	consulAddr = consulCmd.PersistentFlags().String("address", "", "consul address to use, default is CONSUL_HTTP_ADDR")
-	consulDatacenter = consulCmd.PersistentFlags().StringP("datacenter", "d", "", "for cross region specify data center or default will be used")
+	consulDatacenters = consulCmd.PersistentFlags().StringArrayP("datacenter", "d", []string{}, "for cross region specify data center or default will be used, repeat to search multiple (-d dc1 -d dc2)")
+	consulAllDatacenters = consulCmd.PersistentFlags().Bool("all-datacenters", false, "search concurrently in all datacenters known to the agent")
 	consulQuery = consulCmd.PersistentFlags().StringP("query", "q", "", "search query regex supported")
 	consulPrefix = consulCmd.PersistentFlags().StringP("prefix", "p", "/", "the prefix the search query starts from")
 	consulWebOutput = consulCmd.PersistentFlags().Bool("output-url", true, "Output the results with clickable URL links")
+	consulExportFile = consulCmd.Flags().String("export", "", "write matched KV pairs to a file in 'consul kv export' JSON format")
 
-	consulFilterKV = consulCmd.PersistentFlags().Bool("filter-kv", true, "compare query input against the key name in the Consul KV engine")
+	// auth, tls and enterprise
+	consulToken = consulCmd.PersistentFlags().String("token", "", "ACL token, default is CONSUL_HTTP_TOKEN or the token stored in the OS keychain")
+	consulTokenFile = consulCmd.PersistentFlags().String("token-file", "", "file containing the ACL token, default is CONSUL_HTTP_TOKEN_FILE")
+	consulUpdateCreds = consulCmd.PersistentFlags().Bool("update-creds", false, "update the ACL token stored locally on your OS keyring")
+	consulNamespace = consulCmd.PersistentFlags().String("namespace", "", "consul enterprise namespace, default is CONSUL_NAMESPACE")
+	consulPartition = consulCmd.PersistentFlags().String("partition", "", "consul enterprise admin partition, default is CONSUL_PARTITION")
+	consulAllNamespaces = consulCmd.PersistentFlags().Bool("all-namespaces", false, "search in all namespaces of the partition (consul enterprise)")
+	consulCACert = consulCmd.PersistentFlags().String("ca-cert", "", "path to a CA file for TLS, default is CONSUL_CACERT")
+	consulCAPath = consulCmd.PersistentFlags().String("ca-path", "", "path to a directory of CA certificates for TLS, default is CONSUL_CAPATH")
+	consulClientCert = consulCmd.PersistentFlags().String("client-cert", "", "path to a client cert file for TLS, default is CONSUL_CLIENT_CERT")
+	consulClientKey = consulCmd.PersistentFlags().String("client-key", "", "path to a client key file for TLS, default is CONSUL_CLIENT_KEY")
+	consulTLSServerName = consulCmd.PersistentFlags().String("tls-server-name", "", "server name to use as the SNI host for TLS, default is CONSUL_TLS_SERVER_NAME")
+	consulTLSSkipVerify = consulCmd.PersistentFlags().Bool("tls-skip-verify", false, "skip TLS host verification, default is CONSUL_HTTP_SSL_VERIFY")
 
-	consulCmd.MarkPersistentFlagRequired("query")
+	consulFilterKV = consulCmd.PersistentFlags().Bool("filter-kv", true, "compare query input against the key name in the Consul KV engine (disabled by default when catalog filters are used)")
+	consulFilterServices = consulCmd.PersistentFlags().Bool("filter-services", false, "compare query input against service names in the catalog")
+	consulFilterTags = consulCmd.PersistentFlags().Bool("filter-tags", false, "compare query input against service tags in the catalog")
+	consulFilterSvcMeta = consulCmd.PersistentFlags().Bool("filter-service-meta", false, "compare query input against service meta keys and values (fetches every service)")
+	consulFilterNodes = consulCmd.PersistentFlags().Bool("filter-nodes", false, "compare query input against node names and addresses")
+	consulFilterNodeMeta = consulCmd.PersistentFlags().Bool("filter-node-meta", false, "compare query input against node meta keys and values")
+	consulFilterChecks = consulCmd.PersistentFlags().Bool("filter-checks", false, "compare query input against health check names and outputs")
 }
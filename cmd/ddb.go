@@ -17,10 +17,13 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/isan-rivkin/surf/lib/awsu"
 	accessor "github.com/isan-rivkin/surf/lib/common/jsonutil"
+	protoutil "github.com/isan-rivkin/surf/lib/common/proto"
 	common "github.com/isan-rivkin/surf/lib/search"
 	search "github.com/isan-rivkin/surf/lib/search/ddbsearch"
 	"github.com/isan-rivkin/surf/printer"
@@ -40,6 +43,23 @@ var (
 	ddbFailFast            *bool
 	sanitizeOutput         *bool
 	ddbMultiAWSProfile     *[]string
+	ddbSegments            *int
+	ddbKey                 *string
+	ddbSortKey             *string
+	ddbSortKeyPrefix       *string
+	ddbIndexAttrs          *[]string
+	ddbAttr                *string
+	ddbContains            *string
+	ddbBeginsWith          *string
+	ddbProtoFiles          *[]string
+	ddbProtoImportPaths    *[]string
+	ddbProtoTypes          *[]string
+	ddbDecodeDepth         *int
+	ddbMaxRCUPerSecond     *float64
+	ddbMaxTotalRCU         *float64
+	ddbCheckpoint          *string
+	ddbResume              *string
+	ddbExport              *string
 )
 
 var validDDBOutputs = map[string]bool{
@@ -50,7 +70,7 @@ var validDDBOutputs = map[string]bool{
 // ddbCmd represents the ddb command
 var ddbCmd = &cobra.Command{
 	Use:   "ddb",
-	Short: "Search data in DynamoDB (formats: protobuf, base64, json, binary, bytes)",
+	Short: "Search data in DynamoDB (formats: protobuf, base64, json, binary, bytes, gzip, zstd, snappy, msgpack, cbor)",
 	Long: `
 	
 Search free text patterns inside Bytes, Binary, Protobuf, Base64 and Json formats.
@@ -79,9 +99,72 @@ Search free text patterns inside Bytes, Binary, Protobuf, Base64 and Json format
 	
 	$surf ddb -q val -t my-prefix-table --stop-first-match
 
+=== point lookups by key (GetItem/Query) instead of scanning, also on indexes keyed by customer_id ===
+
+	$surf ddb --key cust-123 -t 'orders|customers'
+	$surf ddb --key cust-123 --sort-key-prefix 2023- -t orders --index customer_id -q shipped
+
+=== filter server side (FilterExpression, case sensitive) on a single attribute, -q optionally matches the returned items ===
+
+	$surf ddb -t orders --attr status --contains SHIPPED
+	$surf ddb -t orders --attr email --begins-with admin@ -q 'example\.(com|io)'
+
+=== decode protobuf binary attributes with descriptors (.proto sources or protoc --descriptor_set_out) ===
+
+	$surf ddb -q cust-123 -t orders --proto-file ./protos/order.proto --proto-import-path ./protos --proto-type payload=shop.v1.Order
+	$surf ddb -q cust-123 -t 'orders|events' --proto-file ./protos.pb --proto-type orders:payload=shop.v1.Order --proto-type events:body=shop.v1.Event
+
+=== large tables: parallel scan with 8 segments per table ===
+
+	$surf ddb -q val -t my-large-table --segments 8
+
+=== production tables: consume at most 50 RCU per second and stop after 10000 RCU ===
+
+	$surf ddb -q val -t prod-orders --max-rcu-per-second 50 --max-total-rcu 10000
+
+=== save scan progress and continue an interrupted search (same query, table pattern, --attr, filters and --decode-depth) ===
+
+	$surf ddb -q val -t my-large-table --checkpoint ./scan.json
+	$surf ddb -q val -t my-large-table --resume ./scan.json
+
+=== search table exports (ExportTableToPointInTime, DynamoDB JSON) in s3 or a local copy without consuming RCU ===
+
+	$surf ddb -q val --export s3://my-exports/orders/
+	$surf ddb -q val -t orders --export ./exports
+
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 
+		if *ddbKey != "" {
+			if *ddbSortKey != "" && *ddbSortKeyPrefix != "" {
+				log.Fatal("--sort-key and --sort-key-prefix can not be used together")
+			}
+			// key lookups return every fetched item unless a query narrows them
+			if ddbQuery == "" && !*ddbMatchAll {
+				ddbQuery = ".*"
+			}
+		}
+		if *ddbContains != "" || *ddbBeginsWith != "" {
+			if *ddbAttr == "" {
+				log.Fatal("--contains and --begins-with require --attr")
+			}
+			if *ddbContains != "" && *ddbBeginsWith != "" {
+				log.Fatal("--contains and --begins-with can not be used together")
+			}
+			// the scan returns only filtered items, all of them are matches unless a query narrows them
+			if ddbQuery == "" && !*ddbMatchAll {
+				ddbQuery = ".*"
+			}
+		}
+		if *ddbExport != "" && (*ddbKey != "" || *ddbContains != "" || *ddbBeginsWith != "" || *ddbResume != "") {
+			log.Fatal("--export can not be used with --key, --contains, --begins-with or --resume")
+		}
+		if *ddbMaxRCUPerSecond < 0 || *ddbMaxTotalRCU < 0 {
+			log.Fatal("--max-rcu-per-second and --max-total-rcu must not be negative")
+		}
+		if len(*ddbProtoTypes) > 0 && len(*ddbProtoFiles) == 0 {
+			log.Fatal("--proto-type requires --proto-file")
+		}
 		if !*ddbListTables {
 			if !*ddbMatchAll && ddbQuery == "" {
 				log.Fatalf("invalid query input empty, use --help or --all")
@@ -94,7 +177,7 @@ Search free text patterns inside Bytes, Binary, Protobuf, Base64 and Json format
 			if _, exist := validDDBOutputs[ddbOutputType]; !exist {
 				log.Fatalf("invalid output type %s only valid %v, use --help", ddbOutputType, validDDBOutputs)
 			}
-			if !*ddbAllowAllTables && tableNamePattern == "" {
+			if !*ddbAllowAllTables && tableNamePattern == "" && *ddbExport == "" {
 				log.Fatal("must use --all-tables explicitly or --table <pattern> flag, use --help")
 			}
 
@@ -111,6 +194,10 @@ Search free text patterns inside Bytes, Binary, Protobuf, Base64 and Json format
 			log.WithError(err).Fatalf("failed creating session in AWS")
 		}
 
+		if *ddbResume != "" && len(auths) > 1 {
+			log.Fatal("--resume continues a single session search and can not be used with multiple --aws-session")
+		}
+
 		for _, auth := range auths {
 
 			// MARSHAL ATTRIBUTES UTILITY https://docs.aws.amazon.com/sdk-for-go/api/service/dynamodb/dynamodbattribute/
@@ -142,45 +229,182 @@ Search free text patterns inside Bytes, Binary, Protobuf, Base64 and Json format
 				s := search.NewSearcher[awsu.DDBApi, common.Matcher](ddb, m, p)
 				i, err := search.NewSearchInput(tableNamePattern, ddbQuery, *ddbFailFast, *ddbIncludeGlobalTables, *ddbStopOnFirstMatch, search.ObjectMatch, parallel)
 				if err != nil {
-					log.WithError(err).Error("failed creating search input")
+					log.WithError(err).Fatalf("failed creating search input")
+				}
+				i = i.WithDecodeDepth(*ddbDecodeDepth).WithCapacityBudget(*ddbMaxRCUPerSecond, *ddbMaxTotalRCU)
+				// with --resume the checkpoint segments are used unless --segments is set
+				if *ddbResume == "" || cmd.Flags().Changed("segments") {
+					i = i.WithSegments(*ddbSegments)
+				}
+				if *ddbAttr != "" {
+					var op awsu.DDBFilterOperator
+					value := *ddbContains
+					if *ddbContains != "" {
+						op = awsu.DDBFilterContains
+					} else if *ddbBeginsWith != "" {
+						op, value = awsu.DDBFilterBeginsWith, *ddbBeginsWith
+					}
+					i = i.WithAttribute(*ddbAttr, op, value)
+				}
+				if len(*ddbProtoFiles) > 0 {
+					if i, err = withDDBProtoDescriptors(i); err != nil {
+						log.WithError(err).Fatalf("failed loading proto descriptors")
+					}
+				}
+				if *ddbKey != "" {
+					sortValue := *ddbSortKey
+					if *ddbSortKeyPrefix != "" {
+						sortValue = *ddbSortKeyPrefix
+					}
+					i = i.WithKeyLookup(&search.KeyLookup{
+						PartitionValue:  *ddbKey,
+						SortValue:       sortValue,
+						SortBeginsWith:  *ddbSortKeyPrefix != "",
+						IndexAttributes: *ddbIndexAttrs,
+					})
+				}
+				if *ddbExport != "" {
+					searchDDBExport(s, i, auth, tui)
+					// exports do not depend on the session
+					return
+				}
+				var checkpoint *search.Checkpoint
+				if *ddbKey == "" && (*ddbCheckpoint != "" || *ddbResume != "") {
+					checkpoint, err = getDDBCheckpoint(i, auth, len(auths) > 1)
+					if err != nil {
+						log.WithError(err).Fatalf("failed loading checkpoint")
+					}
+					if i, err = i.WithCheckpoint(checkpoint); err != nil {
+						log.WithError(err).Fatalf("failed resuming from checkpoint")
+					}
+					log.WithField("checkpoint", checkpoint.Path()).Debug("saving scan progress")
 				}
 				tui.GetLoader().Start("searching dynamodb", "", "green")
 				output, err := s.Search(i)
 				tui.GetLoader().Stop()
 
 				if err != nil {
+					if checkpoint != nil && checkpoint.Save() == nil {
+						log.Errorf("scan progress saved, continue with --resume %s", checkpoint.Path())
+					}
 					log.WithError(err).Fatalf("failed running search on dynamodb")
 				}
 				printDDBSearchOutput(i, output, tui)
+				if checkpoint != nil && output.BudgetExhausted {
+					log.Warnf("scan progress saved, continue with --resume %s", checkpoint.Path())
+				}
 			}
 		}
 	},
 }
 
+// searchDDBExport the s3 client is only created for s3:// exports
+func searchDDBExport(s search.Searcher[awsu.DDBApi, common.Matcher], i *search.Input, auth *awsu.AuthInput, tui printer.TuiController[printer.Loader, printer.Table]) {
+	var api awsu.S3API
+	if strings.HasPrefix(*ddbExport, "s3://") {
+		var err error
+		if api, err = awsu.NewS3RegionalClient(auth); err != nil {
+			log.WithError(err).Fatalf("failed creating s3 session")
+		}
+	}
+	source, err := search.NewExportSource(*ddbExport, api)
+	if err != nil {
+		log.WithError(err).Fatalf("failed opening dynamodb export")
+	}
+	tui.GetLoader().Start("searching dynamodb export", "", "green")
+	output, err := s.SearchExport(source, i)
+	tui.GetLoader().Stop()
+	if err != nil {
+		log.WithError(err).Fatalf("failed searching dynamodb export")
+	}
+	printDDBSearchOutput(i, output, tui)
+}
+
+// getDDBCheckpoint loads --resume or creates a new --checkpoint, with multiple sessions each session has its own checkpoint file
+func getDDBCheckpoint(i *search.Input, auth *awsu.AuthInput, multiSession bool) (*search.Checkpoint, error) {
+	if *ddbResume != "" {
+		return search.LoadCheckpoint(*ddbResume)
+	}
+	path := *ddbCheckpoint
+	if multiSession {
+		path = fmt.Sprintf("%s.%s-%s", path, auth.EffectiveProfile, auth.EffectiveRegion)
+	}
+	return search.NewCheckpoint(path, i), nil
+}
+
+// withDDBProtoDescriptors --proto-type values are [table:]attribute=full.MessageName
+func withDDBProtoDescriptors(i *search.Input) (*search.Input, error) {
+	types := map[string]string{}
+	for _, t := range *ddbProtoTypes {
+		parts := strings.SplitN(t, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid --proto-type %s expected [table:]attribute=package.Message", t)
+		}
+		types[parts[0]] = parts[1]
+	}
+	descriptors, err := protoutil.NewDescriptors(*ddbProtoFiles, *ddbProtoImportPaths)
+	if err != nil {
+		return nil, err
+	}
+	return i.WithProtoDescriptors(descriptors, types)
+}
+
+// fmtDDBMatchedPaths i.e "status (key), note"
+func fmtDDBMatchedPaths(matches []*search.AttributeMatch) string {
+	var paths []string
+	for _, m := range matches {
+		p := printer.ColorHiYellow(m.Path)
+		if m.IsKeyMatch {
+			p += " (key)"
+		}
+		paths = append(paths, p)
+	}
+	return strings.Join(paths, ", ")
+}
+
 func printDDBSearchOutputAsJSON(input *search.Input, output *search.Output) {
 	tablesSearched := map[string]bool{}
 	jsonTable := map[string]any{}
 	for idx, match := range output.Matches {
-		table := map[string]string{}
+		table := map[string]any{}
 		tableKey := "Table"
 		tableVal := match.TableName
 		table[tableKey] = tableVal
+		table["Matched_Path"] = match.MatchedPath
+		table["Matches"] = match.Matches
+		if match.DecodedBy != "" {
+			table["Decoded_By"] = match.DecodedBy
+		}
 		tablesSearched[match.TableName] = true
+		// offsets are into the original values, matched values are not sanitized so the offsets stay valid
+		hasOffsets := map[string]bool{}
+		for _, m := range match.Matches {
+			if !m.IsKeyMatch && len(m.Offsets) > 0 {
+				hasOffsets[m.Path] = true
+			}
+		}
+		item := map[string]string{}
 		for k, v := range match.ObjectData {
 			val := aws.StringValue(v)
-			if sanitizeOutput != nil && *sanitizeOutput {
+			if sanitizeOutput != nil && *sanitizeOutput && !hasOffsets[k] {
 				val = printer.SanitizeASCII(val)
 			}
-			table[k] = val
+			item[k] = val
 		}
+		// item attributes are nested so they never collide with the hit fields
+		table["Item"] = item
 		jsonTable[fmt.Sprintf("hit_%d", idx)] = table
 	}
 
-	summary := map[string]string{
+	summary := map[string]any{
 		"Total_Matches":  fmt.Sprintf("%d", len(output.Matches)),
 		"Tables_Scanned": fmt.Sprintf("%d", len(tablesSearched)),
 		"Query":          input.Value,
 	}
+	if len(output.ConsumedRCU) > 0 {
+		summary["Consumed_RCU"] = output.ConsumedRCU
+		summary["RCU_Budget_Exhausted"] = output.BudgetExhausted
+	}
 
 	j := map[string]any{
 		"Hits":    jsonTable,
@@ -209,34 +433,75 @@ func printDDBSearchOutput(input *search.Input, output *search.Output, tui printe
 		tableKey := fmt.Sprintf("#%d Table", idx+1)
 		tableVal := printer.ColorHiYellow(match.TableName)
 
-		labels = append(labels, tableKey)
+		pathKey := fmt.Sprintf("#%d Matched Path", idx+1)
+		labels = append(labels, tableKey, pathKey)
 		table[tableKey] = tableVal
+		table[pathKey] = fmtDDBMatchedPaths(match.Matches)
+		if match.DecodedBy != "" {
+			decodedKey := fmt.Sprintf("#%d Decoded By", idx+1)
+			labels = append(labels, decodedKey)
+			table[decodedKey] = match.DecodedBy
+		}
 		tablesSearched[match.TableName] = true
+		valueOffsets := map[string][][]int{}
+		keyOffsets := map[string][][]int{}
+		for _, m := range match.Matches {
+			if m.IsKeyMatch {
+				keyOffsets[m.Path] = m.Offsets
+			} else {
+				valueOffsets[m.Path] = m.Offsets
+			}
+		}
 		for k, v := range match.ObjectData {
-			keyLabel := fmt.Sprintf("key.%s", k)
+			keyLabel := fmt.Sprintf("key.%s", printer.Highlight(k, keyOffsets[k], nil))
 			labels = append(labels, keyLabel)
-			val := aws.StringValue(v)
+			var transform func(string) string
 			if sanitizeOutput != nil && *sanitizeOutput {
-				val = printer.SanitizeASCII(val)
+				transform = printer.SanitizeASCII
 			}
-			table[keyLabel] = val
+			table[keyLabel] = printer.Highlight(aws.StringValue(v), valueOffsets[k], transform)
 		}
 	}
 	if ddbOutputType == "pretty" {
 		tui.GetTable().PrintInfoBox(table, labels, true)
 
-		tui.GetTable().PrintInfoBox(
-			map[string]string{
-				"Total Matches":  fmt.Sprintf("%d", len(output.Matches)),
-				"Tables Scanned": fmt.Sprintf("%d", len(tablesSearched)),
-				"Query":          input.Value,
-			},
-			[]string{
-				"Total Matches",
-				"Tables Scanned",
-				"Query",
-			}, true)
+		summary := map[string]string{
+			"Total Matches":  fmt.Sprintf("%d", len(output.Matches)),
+			"Tables Scanned": fmt.Sprintf("%d", len(tablesSearched)),
+			"Query":          input.Value,
+		}
+		summaryLabels := []string{
+			"Total Matches",
+			"Tables Scanned",
+			"Query",
+		}
+		if len(output.ConsumedRCU) > 0 {
+			summary["Consumed RCU"] = fmtDDBConsumedRCU(output.ConsumedRCU)
+			summaryLabels = append(summaryLabels, "Consumed RCU")
+		}
+		if output.BudgetExhausted {
+			summary["RCU Budget"] = printer.ColorHiMagenta("exhausted, results are partial")
+			summaryLabels = append(summaryLabels, "RCU Budget")
+		}
+		tui.GetTable().PrintInfoBox(summary, summaryLabels, true)
+	}
+}
+
+// fmtDDBConsumedRCU one line per table sorted by name and the total
+func fmtDDBConsumedRCU(consumed map[string]float64) string {
+	var tables []string
+	total := 0.0
+	for t, units := range consumed {
+		tables = append(tables, t)
+		total += units
+	}
+	sort.Strings(tables)
+	var lines []string
+	for _, t := range tables {
+		lines = append(lines, fmt.Sprintf("%s: %.1f", t, consumed[t]))
 	}
+	lines = append(lines, fmt.Sprintf("total: %.1f", total))
+	return strings.Join(lines, "\n")
 }
 
 func listDDBTables(ddb awsu.DDBApi, withNonGlobal, withGlobal bool, tui printer.TuiController[printer.Loader, printer.Table]) error {
@@ -275,6 +540,23 @@ func init() {
 	ddbMatchAll = ddbCmd.Flags().Bool("all", false, "match all data (same as using -q '\\\\..*') if used with --query will error")
 	ddbIncludeGlobalTables = ddbCmd.Flags().Bool("include-global-tables", true, "if true will include global tables during search")
 	ddbStopOnFirstMatch = ddbCmd.Flags().Bool("stop-first-match", false, "if true stop stop searching on first match found")
+	ddbSegments = ddbCmd.Flags().Int("segments", 1, "parallel scan segments per table (Segment/TotalSegments), higher is faster but consumes read capacity faster")
+	ddbKey = ddbCmd.Flags().String("key", "", "lookup items by partition key value (GetItem/Query) instead of scanning, -q optionally filters the fetched items")
+	ddbSortKey = ddbCmd.Flags().String("sort-key", "", "with --key the exact sort key value")
+	ddbSortKeyPrefix = ddbCmd.Flags().String("sort-key-prefix", "", "with --key match sort keys beginning with the value (begins_with)")
+	ddbIndexAttrs = ddbCmd.Flags().StringArray("index", []string{}, "with --key also query GSI/LSI whose partition key attribute is named (usage: --index customer_id --index email)")
+	ddbAttr = ddbCmd.Flags().String("attr", "", "match only this attribute and its nested paths (i.e address or address.city), with --contains/--begins-with the filter runs server side as a FilterExpression")
+	ddbContains = ddbCmd.Flags().String("contains", "", "with --attr return only items where the attribute contains the value (server side, case sensitive)")
+	ddbBeginsWith = ddbCmd.Flags().String("begins-with", "", "with --attr return only items where the attribute begins with the value (server side, case sensitive)")
+	ddbProtoFiles = ddbCmd.Flags().StringArray("proto-file", []string{}, ".proto source or compiled FileDescriptorSet used to decode binary attributes (without it binary protobuf is shown as field numbers)")
+	ddbProtoImportPaths = ddbCmd.Flags().StringArray("proto-import-path", []string{}, "import paths for resolving imports in --proto-file sources")
+	ddbProtoTypes = ddbCmd.Flags().StringArray("proto-type", []string{}, "message type of a binary attribute (usage: --proto-type payload=shop.v1.Order or per table --proto-type orders:payload=shop.v1.Order)")
+	ddbDecodeDepth = ddbCmd.Flags().Int("decode-depth", search.DefaultDecodeDepth, "max nested encodings to decode per attribute (gzip, zstd, snappy, msgpack, cbor, base64 strings) i.e base64 > gzip > json, 0 disables")
+	ddbMaxRCUPerSecond = ddbCmd.Flags().Float64("max-rcu-per-second", 0, "throttle scans and key lookups to consume at most this read capacity per second across all tables, page size is limited to match the rate (0 is unlimited)")
+	ddbMaxTotalRCU = ddbCmd.Flags().Float64("max-total-rcu", 0, "stop scanning and key lookups once this read capacity was consumed across all tables, results are partial (0 is unlimited)")
+	ddbCheckpoint = ddbCmd.Flags().String("checkpoint", "", "save scan progress (last key per table and segment) and hits to this file, progress is not saved unless set")
+	ddbResume = ddbCmd.Flags().String("resume", "", "continue a previous search from its checkpoint file, hits found before are included in the output")
+	ddbExport = ddbCmd.Flags().String("export", "", "search DynamoDB JSON table exports instead of the tables (no read capacity consumed), s3://bucket/prefix or a local directory, -t filters exported tables, ION exports are skipped")
 	sanitizeOutput = ddbCmd.Flags().Bool("sanitize", true, "if true will remove all non-ascii charts from outputs")
 	ddbAllowAllTables = ddbCmd.Flags().Bool("all-tables", false, "when not providing --table pattern this flag required (potentially expensive)")
 }
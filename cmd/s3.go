@@ -17,9 +17,13 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/isan-rivkin/surf/lib/awsu"
+	commonutil "github.com/isan-rivkin/surf/lib/common"
+	accessor "github.com/isan-rivkin/surf/lib/common/jsonutil"
 	common "github.com/isan-rivkin/surf/lib/search"
 	search "github.com/isan-rivkin/surf/lib/search/s3search"
 	printer "github.com/isan-rivkin/surf/printer"
@@ -33,6 +37,30 @@ var (
 	keyPrefix         string
 	s3WebOutput       *bool
 	allowAllBuckets   *bool
+	s3SearchContent   *bool
+	s3KeyPattern      *string
+	s3MaxContentSize  *string
+	s3ContentExts     *[]string
+	s3ContentTypes    *[]string
+	s3SearchMetadata  *bool
+	s3MetadataTags    *bool
+	s3MetadataLimit   *int
+	s3SearchVersions  *bool
+	s3ShardDepth      *int
+	s3StopFirstMatch  *bool
+	s3Inventory       *string
+	s3InventoryCols   *[]string
+	s3MinSize         *string
+	s3MaxSize         *string
+	s3ModifiedWithin  *string
+	s3OlderThan       *string
+	s3StorageClasses  *[]string
+	s3OutputType      *string
+	s3BucketConfigs   *[]string
+	s3ShowConfig      *bool
+	s3TreeOutput      *bool
+	s3SummaryOutput   *bool
+	s3MaxDepth        *int
 )
 
 // s3Cmd represents the s3 command
@@ -58,6 +86,38 @@ var s3Cmd = &cobra.Command{
 
 	$surf s3  -q '\.json$' -b '^(prod)(.*)-public'
 
+=== search inside objects content (gzip/zstd are decompressed) ===
+
+	$surf s3 -q 'api\.internal\.example\.com' -b configs --content --ext yaml --ext json
+	$surf s3 -q 'endpoint' -b configs --content --key-pattern '^prod/' --content-max-size 1MB
+
+=== search objects user metadata (x-amz-meta-*), content type and tags ===
+
+	$surf s3 -q 'team-payments' -b artifacts --metadata --tags
+	$surf s3 -q 'application/zip' -b artifacts --metadata --prefix releases/ --metadata-limit 500
+
+=== huge buckets: list 2 levels of prefixes (/) concurrently and stop on the first match ===
+
+	$surf s3 -q 'events/2023-01-01/.*\.parquet$' -b datalake --shard-depth 2 --stop-first-match
+
+=== search an S3 Inventory report instead of listing, from s3 or a local copy (CSV, ORC or Parquet) ===
+
+	$surf s3 -q '\.parquet$' --inventory s3://inventories/datalake/daily/2023-01-03T00-00Z/manifest.json
+	$surf s3 -q 'GLACIER' --inventory ./inventory/2023-01-03T00-00Z --inventory-columns StorageClass
+
+=== filter objects by size, age and storage class, output as json with totals per bucket ===
+
+	$surf s3 -q '\.log$' -b logs --min-size 100MB --older-than 1y --storage-class STANDARD -o json
+
+=== group many matches by prefix as a tree or count them per prefix ===
+
+	$surf s3 -q '\.log$' -b logs --tree --max-depth 3
+	$surf s3 -q '\.log$' -b logs --summary --max-depth 2
+
+=== search all object versions and delete markers (who deleted a key and when) ===
+
+	$surf s3 -q 'app\.yaml$' -b configs --prefix configs/prod/ --versions
+
 	` + getEnvVarConfig("s3"),
 	Run: func(cmd *cobra.Command, args []string) {
 		tui := buildTUI()
@@ -73,18 +133,55 @@ var s3Cmd = &cobra.Command{
 
 		for _, auth := range auths {
 
-			s3Client, err := awsu.NewS3(auth)
+			// buckets are accessed through a client in their own region, the session region only lists buckets
+			api, err := awsu.NewS3RegionalClient(auth)
 
 			if err != nil {
 				log.WithError(err).Fatalf("failed creating S3 client")
 			}
 
-			api := awsu.NewS3Client(s3Client)
+			if *s3Inventory != "" {
+				searchS3Inventory(api, auth, tui)
+				continue
+			}
+
 			parallel := 30
 
 			bucketName = *getEnvOrOverride(&bucketName, EnvKeyS3DefaultBucket)
 
 			input := search.NewSearchInput(bucketName, keyPrefix, filterQuery, parallel, *allowAllBuckets)
+			if countTrue(*s3SearchContent, *s3SearchMetadata, *s3SearchVersions) > 1 {
+				log.Fatalf("--content, --metadata and --versions can not be used together")
+			}
+			if *s3SearchVersions {
+				input = input.WithVersionsSearch()
+			}
+			input = input.WithShardDepth(*s3ShardDepth)
+			objectFilter, err := getS3ObjectFilter()
+			if err != nil {
+				log.WithError(err).Fatalf("invalid object filters")
+			}
+			input = input.WithObjectFilter(objectFilter)
+			if *s3StopFirstMatch {
+				input = input.WithStopOnFirstMatch()
+			}
+			if *s3SearchMetadata {
+				input = input.WithMetadataSearch(*s3KeyPattern, &search.MetadataOptions{
+					WithTags: *s3MetadataTags,
+					Limit:    *s3MetadataLimit,
+				})
+			}
+			if *s3SearchContent {
+				maxSize, err := commonutil.ParseByteSize(*s3MaxContentSize)
+				if err != nil {
+					log.WithError(err).Fatalf("invalid --content-max-size")
+				}
+				input = input.WithContentSearch(*s3KeyPattern, &search.ContentFilter{
+					MaxObjectSize: maxSize,
+					Extensions:    *s3ContentExts,
+					ContentTypes:  *s3ContentTypes,
+				})
+			}
 			m := common.NewDefaultRegexMatcher()
 			s := search.NewSearcher[awsu.S3API, common.Matcher](api, m)
 
@@ -102,6 +199,27 @@ var s3Cmd = &cobra.Command{
 				log.WithError(err).Fatalf(msg)
 			}
 
+			if *s3SearchContent {
+				printS3ContentMatches(output, auth, tui)
+				continue
+			}
+			if *s3SearchMetadata {
+				printS3MetadataMatches(output, auth, tui)
+				continue
+			}
+			if *s3SearchVersions {
+				printS3VersionMatches(output, auth, tui)
+				continue
+			}
+
+			if *s3OutputType == "json" {
+				printS3ObjectsJSON(output, auth)
+				continue
+			}
+			if *s3TreeOutput || *s3SummaryOutput {
+				printS3KeysTree(output, *s3SummaryOutput, *s3MaxDepth)
+				continue
+			}
 			if !*s3WebOutput {
 				for bucketName, matchedKeys := range output.BucketToMatches {
 					for _, k := range matchedKeys {
@@ -110,7 +228,7 @@ var s3Cmd = &cobra.Command{
 				}
 				return
 			}
-			labelsOrder := []string{"Match", "Bucket", "AWS Session", "Num #"}
+			labelsOrder := []string{"Match", "Bucket", "Region", "AWS Session", "Num #", "Total Size"}
 			labelsOrderSummary := []string{"Bucket", "Query"}
 			tables := []map[string]string{}
 			summaryTable := map[string]string{
@@ -126,7 +244,8 @@ var s3Cmd = &cobra.Command{
 				matches := fmt.Sprintf("%d", len(matchedKeys))
 				bucketInfo["Bucket"] = bucketName
 				bucketInfo["Num #"] = matches
-				bucketInfo["AWS Session"] = fmt.Sprintf("%s %s", auth.EffectiveProfile, auth.EffectiveRegion)
+				bucketInfo["AWS Session"] = fmtAWSSession(auth, " ")
+				bucketInfo["Region"] = getS3BucketRegion(output, bucketName, auth)
 				summaryTable[bucketName] = matches
 				labelsOrderSummary = append(labelsOrderSummary, bucketName)
 
@@ -134,12 +253,16 @@ var s3Cmd = &cobra.Command{
 					continue
 				}
 
-				for _, k := range matchedKeys {
-					url := awsu.GenerateS3WebURL(bucketName, auth.EffectiveRegion, k)
+				var totalSize int64
+				for _, o := range output.BucketToObjects[bucketName] {
+					url := awsu.GenerateS3WebURL(bucketName, getS3BucketRegion(output, bucketName, auth), o.Key)
 					url = printer.FmtURL(url)
+					details := fmt.Sprintf("  %s %s %s", commonutil.FmtByteSize(o.Size), o.LastModified.Format(time.RFC3339), fmtS3StorageClass(o.StorageClass))
 					val := bucketInfo["Match"]
-					bucketInfo["Match"] = fmt.Sprintf("%s\n%s", val, url)
+					bucketInfo["Match"] = fmt.Sprintf("%s\n%s\n%s", val, url, printer.ColorHiYellow(details))
+					totalSize += o.Size
 				}
+				bucketInfo["Total Size"] = commonutil.FmtByteSize(totalSize)
 				tables = append(tables, bucketInfo)
 			}
 
@@ -154,6 +277,418 @@ var s3Cmd = &cobra.Command{
 	},
 }
 
+func printS3ContentMatches(output *search.Output, auth *awsu.AuthInput, tui printer.TuiController[printer.Loader, printer.Table]) {
+	labelsOrder := []string{"Match", "Bucket", "Region", "AWS Session", "Num #"}
+	for bucketName, matches := range output.BucketToContentMatches {
+		if len(matches) == 0 {
+			continue
+		}
+		if !*s3WebOutput {
+			for _, m := range matches {
+				fmt.Printf("s3://%s/%s:%d: %s\n", bucketName, m.Key, m.Line, m.Snippet)
+			}
+			continue
+		}
+		bucketInfo := map[string]string{
+			"Bucket":      bucketName,
+			"Region":      getS3BucketRegion(output, bucketName, auth),
+			"Num #":       fmt.Sprintf("%d", len(matches)),
+			"AWS Session": fmtAWSSession(auth, " "),
+		}
+		var lines []string
+		prevKey := ""
+		for _, m := range matches {
+			if m.Key != prevKey {
+				url := awsu.GenerateS3WebURL(bucketName, getS3BucketRegion(output, bucketName, auth), m.Key)
+				lines = append(lines, printer.FmtURL(url))
+				prevKey = m.Key
+			}
+			lines = append(lines, fmt.Sprintf("  %s %s", printer.ColorHiYellow(fmt.Sprintf("%d:", m.Line)), m.Snippet))
+		}
+		bucketInfo["Match"] = strings.Join(lines, "\n")
+		tui.GetTable().PrintInfoBox(bucketInfo, labelsOrder, true)
+	}
+}
+
+func printS3MetadataMatches(output *search.Output, auth *awsu.AuthInput, tui printer.TuiController[printer.Loader, printer.Table]) {
+	labelsOrder := []string{"Match", "Bucket", "Region", "AWS Session", "Num #"}
+	for bucketName, matches := range output.BucketToMetadataMatches {
+		if len(matches) == 0 {
+			continue
+		}
+		if !*s3WebOutput {
+			for _, m := range matches {
+				fmt.Printf("s3://%s/%s %s=%s\n", bucketName, m.Key, m.Attribute, m.Value)
+			}
+			continue
+		}
+		keys := map[string]bool{}
+		var lines []string
+		prevKey := ""
+		for _, m := range matches {
+			if m.Key != prevKey {
+				url := awsu.GenerateS3WebURL(bucketName, getS3BucketRegion(output, bucketName, auth), m.Key)
+				lines = append(lines, printer.FmtURL(url))
+				prevKey = m.Key
+				keys[m.Key] = true
+			}
+			lines = append(lines, fmt.Sprintf("  %s %s", printer.ColorHiYellow(m.Attribute+":"), m.Value))
+		}
+		tui.GetTable().PrintInfoBox(map[string]string{
+			"Bucket":      bucketName,
+			"Region":      getS3BucketRegion(output, bucketName, auth),
+			"Num #":       fmt.Sprintf("%d", len(keys)),
+			"AWS Session": fmtAWSSession(auth, " "),
+			"Match":       strings.Join(lines, "\n"),
+		}, labelsOrder, true)
+	}
+}
+
+var s3BucketsCmd = &cobra.Command{
+	Use:   "buckets",
+	Short: "pattern matching against buckets configuration (policy, acl, public access block, encryption, versioning, lifecycle, tags)",
+	Long: `
+Match the query against the configuration documents of buckets, reporting which configuration matched.
+Configurations that are not set on a bucket are matched as "` + awsu.BucketConfigNotConfigured + `".
+
+=== which buckets grant access to an account ===
+
+	$surf s3 buckets -q '123456789012' --all-buckets --config policy --config acl
+
+=== which buckets are not encrypted / not versioned ===
+
+	$surf s3 buckets -q '^` + awsu.BucketConfigNotConfigured + `$' -b prod --config encryption --config versioning
+
+=== show the matched documents ===
+
+	$surf s3 buckets -q 'BlockPublicAcls":false' -b '.*' --show-config
+	`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		configs, err := getS3BucketConfigTypes()
+		if err != nil {
+			log.WithError(err).Fatalf("invalid --config")
+		}
+		tui := buildTUI()
+		sessionInputs, err := resolveAWSSessions(s3MultiAWSProfile, awsProfile, awsRegion)
+		if err != nil {
+			log.WithError(err).Fatalf("failed building input for AWS session")
+		}
+		auths, err := awsu.NewSessionInputMatrix(sessionInputs)
+		if err != nil {
+			log.WithError(err).Fatalf("failed creating session in AWS")
+		}
+
+		for _, auth := range auths {
+			api, err := awsu.NewS3RegionalClient(auth)
+			if err != nil {
+				log.WithError(err).Fatalf("failed creating S3 client")
+			}
+			bucketName = *getEnvOrOverride(&bucketName, EnvKeyS3DefaultBucket)
+			input := search.NewSearchInput(bucketName, "", filterQuery, 30, *allowAllBuckets).WithBucketConfigs(configs)
+			s := search.NewSearcher[awsu.S3API, common.Matcher](api, common.NewDefaultRegexMatcher())
+
+			tui.GetLoader().Start("searching s3 buckets configuration", "", "green")
+			output, err := s.SearchBuckets(input)
+			tui.GetLoader().Stop()
+
+			if err != nil {
+				msg := "error while searching buckets"
+				if err.Error() == search.TooManyBucketsErr {
+					msg = "too many buckets, use --bucket <pattern> to filter buckets or use --all-buckets to allow anyway (discouraged)"
+				}
+				log.WithError(err).Fatalf(msg)
+			}
+			printS3BucketConfigMatches(output, auth, tui)
+		}
+	},
+}
+
+func getS3BucketConfigTypes() ([]awsu.BucketConfigType, error) {
+	var configs []awsu.BucketConfigType
+	for _, c := range *s3BucketConfigs {
+		valid := false
+		for _, t := range awsu.AllBucketConfigTypes {
+			if string(t) == strings.ToLower(c) {
+				configs = append(configs, t)
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown configuration %s, valid options %v", c, awsu.AllBucketConfigTypes)
+		}
+	}
+	return configs, nil
+}
+
+func printS3BucketConfigMatches(output *search.Output, auth *awsu.AuthInput, tui printer.TuiController[printer.Loader, printer.Table]) {
+	buckets := []string{}
+	for b := range output.BucketToConfigMatches {
+		buckets = append(buckets, b)
+	}
+	sort.Strings(buckets)
+
+	labelsOrder := []string{"Bucket", "Matched", "Region", "AWS Session"}
+	for _, bucketName := range buckets {
+		matches := output.BucketToConfigMatches[bucketName]
+		var configs []string
+		for _, m := range matches {
+			configs = append(configs, string(m.Config))
+		}
+		if !*s3WebOutput {
+			fmt.Printf("s3://%s %s\n", bucketName, strings.Join(configs, ","))
+			continue
+		}
+		region := getS3BucketRegion(output, bucketName, auth)
+		info := map[string]string{
+			"Bucket":      printer.FmtURL(awsu.GenerateS3BucketWebURL(bucketName, region)),
+			"Matched":     strings.Join(configs, ", "),
+			"Region":      region,
+			"AWS Session": fmtAWSSession(auth, " "),
+		}
+		labels := labelsOrder
+		if *s3ShowConfig {
+			for _, m := range matches {
+				label := string(m.Config)
+				info[label] = m.Document
+				if container, err := accessor.NewJsonContainerFromBytes([]byte(m.Document)); err == nil {
+					info[label] = container.StringIndent("", "  ")
+				}
+				labels = append(labels, label)
+			}
+		}
+		tui.GetTable().PrintInfoBox(info, labels, true)
+	}
+}
+
+// printS3KeysTree prints matched keys grouped by prefix, in summary mode only the counts per prefix
+func printS3KeysTree(output *search.Output, summary bool, maxDepth int) {
+	buckets := []string{}
+	for b, keys := range output.BucketToMatches {
+		if len(keys) > 0 {
+			buckets = append(buckets, b)
+		}
+	}
+	sort.Strings(buckets)
+	for _, bucketName := range buckets {
+		keys := output.BucketToMatches[bucketName]
+		fmt.Printf("%s (%d)\n", printer.ColorHiBlue("s3://"+bucketName), len(keys))
+		if !summary {
+			for _, line := range search.NewKeyTree(keys).Render(maxDepth) {
+				fmt.Println(line)
+			}
+			continue
+		}
+		counts := search.PrefixCounts(keys, maxDepth)
+		prefixes := []string{}
+		for p := range counts {
+			prefixes = append(prefixes, p)
+		}
+		sort.Strings(prefixes)
+		for _, p := range prefixes {
+			fmt.Printf("  %6d  %s\n", counts[p], "/"+p)
+		}
+	}
+}
+
+func getS3ObjectFilter() (*search.ObjectFilter, error) {
+	filter := &search.ObjectFilter{StorageClasses: *s3StorageClasses}
+	var err error
+	if filter.MinSize, err = commonutil.ParseByteSize(*s3MinSize); err != nil {
+		return nil, err
+	}
+	if filter.MaxSize, err = commonutil.ParseByteSize(*s3MaxSize); err != nil {
+		return nil, err
+	}
+	if *s3ModifiedWithin != "" {
+		if filter.ModifiedWithin, err = commonutil.ParseHumanDuration(*s3ModifiedWithin); err != nil {
+			return nil, err
+		}
+	}
+	if *s3OlderThan != "" {
+		if filter.OlderThan, err = commonutil.ParseHumanDuration(*s3OlderThan); err != nil {
+			return nil, err
+		}
+	}
+	return filter, nil
+}
+
+func fmtS3StorageClass(class string) string {
+	if class == "" {
+		return "STANDARD"
+	}
+	return class
+}
+
+func printS3ObjectsJSON(output *search.Output, auth *awsu.AuthInput) {
+	buckets := []map[string]any{}
+	for bucketName, objects := range output.BucketToObjects {
+		var totalSize int64
+		jsonObjects := []map[string]any{}
+		for _, o := range objects {
+			totalSize += o.Size
+			jsonObjects = append(jsonObjects, map[string]any{
+				"key":           o.Key,
+				"size":          o.Size,
+				"last_modified": o.LastModified.Format(time.RFC3339),
+				"storage_class": fmtS3StorageClass(o.StorageClass),
+			})
+		}
+		buckets = append(buckets, map[string]any{
+			"bucket":        bucketName,
+			"region":        getS3BucketRegion(output, bucketName, auth),
+			"objects":       jsonObjects,
+			"total_objects": len(objects),
+			"total_size":    totalSize,
+		})
+	}
+	sort.SliceStable(buckets, func(a, b int) bool {
+		return buckets[a]["bucket"].(string) < buckets[b]["bucket"].(string)
+	})
+	container, err := accessor.NewJsonContainerFromInterface("result", map[string]any{
+		"aws_session": fmtAWSSession(auth, " "),
+		"buckets":     buckets,
+	})
+	if err != nil {
+		log.WithError(err).Fatalf("failed creating json container")
+	}
+	fmt.Println(container.String())
+}
+
+func searchS3Inventory(api awsu.S3API, auth *awsu.AuthInput, tui printer.TuiController[printer.Loader, printer.Table]) {
+	s := search.NewInventorySearcher(api, common.NewDefaultRegexMatcher())
+	tui.GetLoader().Start("searching s3 inventory", "", "green")
+	output, err := s.Search(&search.InventoryInput{
+		Manifest: *s3Inventory,
+		Value:    filterQuery,
+		Columns:  *s3InventoryCols,
+		Parallel: 30,
+	})
+	tui.GetLoader().Stop()
+	if err != nil {
+		log.WithError(err).Fatalf("failed searching s3 inventory")
+	}
+
+	cols := []string{"Size", "LastModifiedDate", "StorageClass", "EncryptionStatus"}
+	if !*s3WebOutput {
+		for _, m := range output.Matches {
+			line := fmt.Sprintf("s3://%s/%s", m.Bucket, m.Key)
+			for _, c := range cols {
+				if v, exist := m.Fields[c]; exist {
+					line += " " + v
+				}
+			}
+			fmt.Println(line)
+		}
+		return
+	}
+	if len(output.Matches) == 0 {
+		return
+	}
+	region, err := api.GetBucketRegion(output.Manifest.SourceBucket)
+	if err != nil {
+		region = auth.EffectiveRegion
+	}
+	var lines []string
+	for _, m := range output.Matches {
+		url := awsu.GenerateS3WebURL(m.Bucket, region, m.Key)
+		lines = append(lines, printer.FmtURL(url))
+		var details []string
+		for _, c := range cols {
+			if v, exist := m.Fields[c]; exist {
+				details = append(details, fmt.Sprintf("%s %s", printer.ColorHiYellow(c+":"), v))
+			}
+		}
+		if m.Column != search.InventoryKeyColumn {
+			details = append(details, fmt.Sprintf("%s %s", printer.ColorHiYellow("Matched:"), m.Column))
+		}
+		lines = append(lines, "  "+strings.Join(details, " "))
+	}
+	tui.GetTable().PrintInfoBox(map[string]string{
+		"Bucket":      output.Manifest.SourceBucket,
+		"Inventory":   *s3Inventory,
+		"AWS Session": fmtAWSSession(auth, " "),
+		"Num #":       fmt.Sprintf("%d", len(output.Matches)),
+		"Match":       strings.Join(lines, "\n"),
+	}, []string{"Match", "Bucket", "Inventory", "AWS Session", "Num #"}, true)
+}
+
+func printS3VersionMatches(output *search.Output, auth *awsu.AuthInput, tui printer.TuiController[printer.Loader, printer.Table]) {
+	labelsOrder := []string{"Match", "Bucket", "Region", "AWS Session", "Num #"}
+	for bucketName, matches := range output.BucketToVersionMatches {
+		if len(matches) == 0 {
+			continue
+		}
+		if !*s3WebOutput {
+			for _, m := range matches {
+				fmt.Printf("s3://%s/%s %s %s %d %s %s\n", bucketName, m.Key, m.VersionID, m.LastModified.Format(time.RFC3339), m.Size, fmtS3VersionState(m), m.Owner)
+			}
+			continue
+		}
+		keys := map[string]bool{}
+		var lines []string
+		prevKey := ""
+		for _, m := range matches {
+			if m.Key != prevKey {
+				url := awsu.GenerateS3WebURL(bucketName, getS3BucketRegion(output, bucketName, auth), m.Key)
+				lines = append(lines, printer.FmtURL(url))
+				prevKey = m.Key
+				keys[m.Key] = true
+			}
+			state := fmtS3VersionState(m)
+			if m.IsDeleteMarker {
+				state = printer.ColorHiYellow(state)
+			}
+			lines = append(lines, fmt.Sprintf("  %s %s %d bytes %s %s", m.LastModified.Format(time.RFC3339), m.VersionID, m.Size, state, m.Owner))
+		}
+		tui.GetTable().PrintInfoBox(map[string]string{
+			"Bucket":      bucketName,
+			"Region":      getS3BucketRegion(output, bucketName, auth),
+			"Num #":       fmt.Sprintf("%d", len(keys)),
+			"AWS Session": fmtAWSSession(auth, " "),
+			"Match":       strings.Join(lines, "\n"),
+		}, labelsOrder, true)
+	}
+}
+
+func fmtS3VersionState(m *search.VersionMatch) string {
+	state := "version"
+	if m.IsDeleteMarker {
+		state = "delete-marker"
+	}
+	if m.IsLatest {
+		state += " (latest)"
+	}
+	return state
+}
+
+func countTrue(flags ...bool) int {
+	count := 0
+	for _, f := range flags {
+		if f {
+			count++
+		}
+	}
+	return count
+}
+
+// getS3BucketRegion returns the detected bucket region falling back to the session region
+func getS3BucketRegion(output *search.Output, bucketName string, auth *awsu.AuthInput) string {
+	if region, exist := output.BucketToRegion[bucketName]; exist && region != "" {
+		return region
+	}
+	return auth.EffectiveRegion
+}
+
+// fmtAWSSession formats the session profile and region with the account id when it can be resolved
+func fmtAWSSession(auth *awsu.AuthInput, sep string) string {
+	session := auth.EffectiveProfile + sep + auth.EffectiveRegion
+	if accountID := auth.GetAccountID(); accountID != "" {
+		session = fmt.Sprintf("%s (%s)", session, accountID)
+	}
+	return session
+}
+
 func resolveAWSSessions(multiple *[]string, profile, region string) ([]*awsu.AWSSessionInput, error) {
 	if multiple != nil && len(*multiple) > 0 {
 		log.Debugf("using multiple aws sessions, got %v", *multiple)
@@ -187,6 +722,9 @@ func inputToMultipleAWSSessions(input []string) ([]*awsu.AWSSessionInput, error)
 
 func init() {
 	rootCmd.AddCommand(s3Cmd)
+	s3Cmd.AddCommand(s3BucketsCmd)
+	s3BucketConfigs = s3BucketsCmd.Flags().StringArray("config", []string{}, fmt.Sprintf("configurations to match against, all if empty %v", awsu.AllBucketConfigTypes))
+	s3ShowConfig = s3BucketsCmd.Flags().Bool("show-config", false, "print the matched configuration documents")
 	s3Cmd.PersistentFlags().StringVarP(&awsProfile, "profile", "p", getDefaultProfileEnvVar(), "~/.aws/credentials chosen account")
 	s3Cmd.PersistentFlags().StringVarP(&awsRegion, "region", "r", "", "~/.aws/config default region if empty")
 	s3Cmd.PersistentFlags().StringVarP(&keyPrefix, "prefix", "k", "", "key prefix to start search from")
@@ -195,5 +733,27 @@ func init() {
 	s3MultiAWSProfile = s3Cmd.PersistentFlags().StringArray("aws-session", []string{}, "search in multiple aws profiles & regions (comma separated: --aws-session default,us-east-1 --aws-session dev-account,us-west-2) - overrides --profile and --region")
 	s3WebOutput = s3Cmd.PersistentFlags().Bool("output-url", true, "Output the results with clickable URL links")
 	allowAllBuckets = s3Cmd.PersistentFlags().Bool("all-buckets", false, "when not providing --bucket pattern this flag required to allow all buckets search")
+	s3SearchContent = s3Cmd.Flags().Bool("content", false, "match the query against objects content (line by line) instead of key names")
+	s3KeyPattern = s3Cmd.Flags().String("key-pattern", "", "with --content or --metadata only read keys matching this regex pattern")
+	s3MaxContentSize = s3Cmd.Flags().String("content-max-size", "10MB", "with --content skip objects larger than this size and stop reading decompressed content past it (0 for no limit)")
+	s3ContentExts = s3Cmd.Flags().StringArray("ext", []string{}, "with --content only read keys with these extensions (usage: --ext json --ext yaml)")
+	s3SearchMetadata = s3Cmd.Flags().Bool("metadata", false, "match the query against objects user metadata names and values, content type and storage class values (HEAD per object)")
+	s3MetadataTags = s3Cmd.Flags().Bool("tags", false, "with --metadata also match against object tags (additional request per object)")
+	s3MetadataLimit = s3Cmd.Flags().Int("metadata-limit", 1000, "with --metadata max objects to inspect per bucket (0 for no limit)")
+	s3SearchVersions = s3Cmd.Flags().Bool("versions", false, "match the query against the keys of all object versions and delete markers (ListObjectVersions)")
+	s3ShardDepth = s3Cmd.Flags().Int("shard-depth", 1, "levels of key prefixes (delimiter /) to split each bucket into and list concurrently (0 lists sequentially)")
+	s3StopFirstMatch = s3Cmd.Flags().Bool("stop-first-match", false, "stop listing once a key matched")
+	s3Inventory = s3Cmd.Flags().String("inventory", "", "search an S3 Inventory manifest.json instead of listing buckets, CSV, ORC or Parquet reports (s3://bucket/path/manifest.json or a local file/dir)")
+	s3InventoryCols = s3Cmd.Flags().StringArray("inventory-columns", []string{}, "with --inventory also match against these columns besides Key (usage: --inventory-columns StorageClass --inventory-columns EncryptionStatus)")
+	s3MinSize = s3Cmd.Flags().String("min-size", "", "only objects larger than size i.e 10MB")
+	s3MaxSize = s3Cmd.Flags().String("max-size", "", "only objects smaller than size i.e 1GB")
+	s3ModifiedWithin = s3Cmd.Flags().String("modified-within", "", "only objects modified within duration i.e 7d, 12h, 2w")
+	s3OlderThan = s3Cmd.Flags().String("older-than", "", "only objects last modified before duration i.e 1y, 30d")
+	s3StorageClasses = s3Cmd.Flags().StringArray("storage-class", []string{}, "only objects in storage class (usage: --storage-class GLACIER --storage-class DEEP_ARCHIVE)")
+	s3OutputType = s3Cmd.Flags().StringP("out", "o", "pretty", "output format for key search [json, pretty]")
+	s3TreeOutput = s3Cmd.Flags().Bool("tree", false, "print matched keys as a tree grouped by prefix")
+	s3SummaryOutput = s3Cmd.Flags().Bool("summary", false, "print only the number of matched keys per prefix")
+	s3MaxDepth = s3Cmd.Flags().Int("max-depth", 2, "with --tree collapse prefixes deeper than this (0 for unlimited), with --summary the prefix levels to count by")
+	s3ContentTypes = s3Cmd.Flags().StringArray("content-type", []string{}, "with --content only read objects with content type containing (usage: --content-type text/ --content-type json)")
 	s3Cmd.MarkPersistentFlagRequired("query")
 }
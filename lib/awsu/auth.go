@@ -3,23 +3,35 @@ package awsu
 import (
 	"context"
 	"fmt"
+	"sync"
 
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	stscredsv2 "github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/acm"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/isan-rivkin/route53-cli/aws_utils"
+	log "github.com/sirupsen/logrus"
 )
 
 type AuthInput struct {
-	Provider         client.ConfigProvider
-	Configs          []*aws.Config
+	// sdk v1 session (acm, ddb)
+	Provider client.ConfigProvider
+	Configs  []*aws.Config
+	// sdk v2 config (s3, cloudcontrol, cloudformation), shares the credentials of Provider
+	ConfigV2         awsv2.Config
 	EffectiveRegion  string
 	EffectiveProfile string
+
+	accountOnce sync.Once
+	accountID   string
 }
 
 type AWSSessionInput struct {
@@ -39,21 +51,106 @@ func NewSessionInputMatrix(inputs []*AWSSessionInput) ([]*AuthInput, error) {
 	return out, nil
 }
 
+// NewSessionInput resolves the profile once with the v2 config loader (shared config, sso cache, credential_process, role_arn/source_profile)
+// and builds the v1 session on top of the same credentials so every client acts as the same identity
 func NewSessionInput(profile, region string) (*AuthInput, error) {
-	sess := aws_utils.GetEnvSession(profile)
-	if sess == nil {
-		return nil, fmt.Errorf("failed creating env sessions profile %s region %s", profile, region)
+	opts := []func(*config.LoadOptions) error{
+		config.WithAssumeRoleCredentialOptions(func(o *stscredsv2.AssumeRoleOptions) {
+			o.TokenProvider = stscredsv2.StdinTokenProvider
+		}),
+	}
+	if profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
 	}
-	effectiveRegion := aws.StringValue(sess.Config.Region)
-
-	c := aws.NewConfig()
 	if region != "" {
-		c = c.WithRegion(region)
-		effectiveRegion = region
+		opts = append(opts, config.WithRegion(region))
+	}
+	confV2, err := config.LoadDefaultConfig(context.TODO(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading aws config profile %s region %s %s", profile, region, err.Error())
+	}
+	effectiveRegion := confV2.Region
+
+	c := aws.NewConfig().WithCredentials(credentials.NewCredentials(&v2CredentialsProvider{provider: confV2.Credentials}))
+	if effectiveRegion != "" {
+		c = c.WithRegion(effectiveRegion)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Profile:           profile,
+		SharedConfigState: session.SharedConfigEnable,
+		Config:            *c,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed creating env sessions profile %s region %s %s", profile, region, err.Error())
 	}
 	conf := []*aws.Config{c}
 
-	return &AuthInput{Provider: sess, Configs: conf, EffectiveRegion: effectiveRegion, EffectiveProfile: profile}, nil
+	return &AuthInput{Provider: sess, Configs: conf, ConfigV2: confV2, EffectiveRegion: effectiveRegion, EffectiveProfile: profile}, nil
+}
+
+// GetAccountID returns the account id of the session identity, empty if it could not be resolved
+func (in *AuthInput) GetAccountID() string {
+	in.accountOnce.Do(func() {
+		out, err := sts.NewFromConfig(in.ConfigV2).GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+		if err != nil {
+			log.WithError(err).WithField("profile", in.EffectiveProfile).Debug("failed resolving aws account id")
+			return
+		}
+		in.accountID = awsv2.ToString(out.Account)
+	})
+	return in.accountID
+}
+
+// configForRegion returns a copy of the v2 config targeting another region
+func (in *AuthInput) configForRegion(region string) awsv2.Config {
+	conf := in.ConfigV2.Copy()
+	if region != "" {
+		conf.Region = region
+	}
+	return conf
+}
+
+// v2CredentialsProvider exposes sdk v2 credentials to sdk v1 clients
+type v2CredentialsProvider struct {
+	credentials.Expiry
+	provider awsv2.CredentialsProvider
+	// static credentials never expire, the zero Expiry would otherwise retrieve them again on every request
+	retrieved bool
+	canExpire bool
+}
+
+func (p *v2CredentialsProvider) IsExpired() bool {
+	if !p.retrieved {
+		return true
+	}
+	if !p.canExpire {
+		return false
+	}
+	return p.Expiry.IsExpired()
+}
+
+func (p *v2CredentialsProvider) Retrieve() (credentials.Value, error) {
+	return p.RetrieveWithContext(context.TODO())
+}
+
+func (p *v2CredentialsProvider) RetrieveWithContext(ctx credentials.Context) (credentials.Value, error) {
+	if p.provider == nil {
+		return credentials.Value{}, fmt.Errorf("no aws credentials found")
+	}
+	creds, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	if creds.CanExpire {
+		p.SetExpiration(creds.Expires, 0)
+	}
+	p.retrieved, p.canExpire = true, creds.CanExpire
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		ProviderName:    creds.Source,
+	}, nil
 }
 
 func NewACM(in *AuthInput) (*acm.ACM, error) {
@@ -65,13 +162,12 @@ func NewACM(in *AuthInput) (*acm.ACM, error) {
 }
 
 func NewS3(in *AuthInput) (*s3.Client, error) {
-	conf, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(in.EffectiveRegion))
-
-	if err != nil {
-		return nil, fmt.Errorf("failed loading aws config %s", err.Error())
-	}
+	return NewS3ForRegion(in, in.EffectiveRegion)
+}
 
-	s := s3.NewFromConfig(conf)
+// NewS3ForRegion creates a client for buckets outside of the session region
+func NewS3ForRegion(in *AuthInput, region string) (*s3.Client, error) {
+	s := s3.NewFromConfig(in.configForRegion(region))
 
 	if s == nil {
 		return nil, fmt.Errorf("failed creating s3 client")
@@ -88,11 +184,7 @@ func NewDDB(in *AuthInput) (*dynamodb.DynamoDB, error) {
 }
 
 func NewCloudControl(in *AuthInput) (*cloudcontrol.Client, error) {
-	conf, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(in.EffectiveRegion))
-	if err != nil {
-		return nil, fmt.Errorf("failed loading aws config %s", err.Error())
-	}
-	cc := cloudcontrol.NewFromConfig(conf)
+	cc := cloudcontrol.NewFromConfig(in.ConfigV2)
 	if cc == nil {
 		return nil, fmt.Errorf("failed creating cloudcontrol client")
 	}
@@ -100,11 +192,7 @@ func NewCloudControl(in *AuthInput) (*cloudcontrol.Client, error) {
 }
 
 func NewCloudFormation(in *AuthInput) (*cloudformation.Client, error) {
-	conf, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(in.EffectiveRegion))
-	if err != nil {
-		return nil, fmt.Errorf("failed loading aws config %s", err.Error())
-	}
-	cf := cloudformation.NewFromConfig(conf)
+	cf := cloudformation.NewFromConfig(in.ConfigV2)
 	if cf == nil {
 		return nil, fmt.Errorf("failed creating cloudformation client")
 	}
@@ -0,0 +1,44 @@
+package awsu
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/magiconair/properties/assert"
+)
+
+// countingProvider v2 credentials provider counting Retrieve calls
+type countingProvider struct {
+	creds awsv2.Credentials
+	calls int
+}
+
+func (p *countingProvider) Retrieve(ctx context.Context) (awsv2.Credentials, error) {
+	p.calls++
+	return p.creds, nil
+}
+
+func TestV2CredentialsProviderExpiry(t *testing.T) {
+	static := &countingProvider{creds: awsv2.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}}
+	creds := credentials.NewCredentials(&v2CredentialsProvider{provider: static})
+	for i := 0; i < 3; i++ {
+		v, err := creds.Get()
+		assert.Equal(t, err, nil)
+		assert.Equal(t, v.AccessKeyID, "AKID")
+	}
+	assert.Equal(t, static.calls, 1, "credentials that can not expire are retrieved once")
+
+	expiring := &countingProvider{creds: awsv2.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", CanExpire: true, Expires: time.Now().Add(-time.Minute)}}
+	creds = credentials.NewCredentials(&v2CredentialsProvider{provider: expiring})
+	_, _ = creds.Get()
+	_, _ = creds.Get()
+	assert.Equal(t, expiring.calls, 2, "expired credentials are retrieved again")
+
+	expiring.creds.Expires = time.Now().Add(time.Hour)
+	_, _ = creds.Get()
+	_, _ = creds.Get()
+	assert.Equal(t, expiring.calls, 3)
+}
@@ -1,7 +1,10 @@
 package awsu
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -26,6 +29,16 @@ type DDBTableDescriber interface {
 	GetRawGlobalTableDescriber() *dynamodb.DescribeGlobalTableOutput
 	GetRawTableDescriber() *dynamodb.DescribeTableOutput
 	GetSchemaDefinitions() (map[string]*DDBSchemaKey, error)
+	GetIndexSchemas() ([]*DDBIndexSchema, error)
+}
+
+// DDBIndexSchema key schema of the table primary key or a secondary index
+type DDBIndexSchema struct {
+	// empty for the table primary key
+	IndexName string
+	HashKey   *DDBSchemaKey
+	// nil if no sort key
+	RangeKey *DDBSchemaKey
 }
 
 type DDBTableWrapper struct {
@@ -109,12 +122,117 @@ func (w *DDBTableWrapper) GetSchemaDefinitions() (map[string]*DDBSchemaKey, erro
 	return result, nil
 }
 
+// GetIndexSchemas returns the primary key schema first followed by global and local secondary indexes
+func (w *DDBTableWrapper) GetIndexSchemas() ([]*DDBIndexSchema, error) {
+	if !w.IsTableDescribed() || w.GetRawTableDescriber() == nil {
+		return nil, fmt.Errorf("table %s not described ", w.TableName())
+	}
+	table := w.GetRawTableDescriber().Table
+	attrTypes := map[string]string{}
+	for _, attr := range table.AttributeDefinitions {
+		attrTypes[aws.StringValue(attr.AttributeName)] = aws.StringValue(attr.AttributeType)
+	}
+	toIndexSchema := func(indexName string, keySchema []*dynamodb.KeySchemaElement) *DDBIndexSchema {
+		idx := &DDBIndexSchema{IndexName: indexName}
+		for _, k := range keySchema {
+			name := aws.StringValue(k.AttributeName)
+			key := &DDBSchemaKey{Name: name, KeyType: attrTypes[name], KeyRole: aws.StringValue(k.KeyType)}
+			if key.KeyRole == dynamodb.KeyTypeHash {
+				idx.HashKey = key
+			} else {
+				idx.RangeKey = key
+			}
+		}
+		return idx
+	}
+
+	result := []*DDBIndexSchema{toIndexSchema("", table.KeySchema)}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		result = append(result, toIndexSchema(aws.StringValue(gsi.IndexName), gsi.KeySchema))
+	}
+	for _, lsi := range table.LocalSecondaryIndexes {
+		result = append(result, toIndexSchema(aws.StringValue(lsi.IndexName), lsi.KeySchema))
+	}
+	return result, nil
+}
+
+// NewKeyAttributeValue converts a key value from the command line to the key attribute type S, N or B
+func NewKeyAttributeValue(keyType, value string) (*dynamodb.AttributeValue, error) {
+	switch keyType {
+	case dynamodb.ScalarAttributeTypeN:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("key value %s is not a number", value)
+		}
+		return &dynamodb.AttributeValue{N: aws.String(value)}, nil
+	case dynamodb.ScalarAttributeTypeB:
+		return &dynamodb.AttributeValue{B: []byte(value)}, nil
+	default:
+		return &dynamodb.AttributeValue{S: aws.String(value)}, nil
+	}
+}
+
+// DDBKeyCondition a lookup by partition key and optionally sort key value or prefix
+type DDBKeyCondition struct {
+	Table      string
+	Index      *DDBIndexSchema
+	HashValue  *dynamodb.AttributeValue
+	RangeValue *dynamodb.AttributeValue
+	// if true RangeValue is matched with begins_with
+	RangeBeginsWith bool
+	// if set the query pages are throttled and their consumed capacity recorded
+	Budget *DDBCapacityBudget
+}
+
 type DDBApi interface {
 	DescribeTable(name string, isGlobal bool) (DDBTableDescriber, error)
 	ListAllTables() ([]string, error)
 	ListAllGlobalTables() ([]*dynamodb.GlobalTable, error)
 	ListCombinedTables(fetchNonGlobal, fetchGlobal bool) ([]DDBTableDescriber, error)
 	ScanTable(name string, pageHandler DDBAttributesHandler) error
+	ScanTableWithOptions(name string, opts *DDBScanOptions, pageHandler DDBAttributesHandler) error
+	GetItem(table string, key map[string]*dynamodb.AttributeValue, budget *DDBCapacityBudget) (map[string]*dynamodb.AttributeValue, error)
+	QueryTable(cond *DDBKeyCondition, pageHandler DDBAttributesHandler) error
+}
+
+// DDBScanOptions tune how a table is scanned
+type DDBScanOptions struct {
+	// parallel scan segments (Segment/TotalSegments) each scanned by its own routine, <= 1 scans sequentially
+	Segments int
+	// server side filter, items are still read (and billed) but only matching items are returned
+	Filter *DDBFilter
+	// if set consumed capacity is recorded per page, fetches are throttled and the scan stops when the total budget is exhausted
+	Budget *DDBCapacityBudget
+	// ExclusiveStartKey per segment to continue a previous scan
+	StartKeys map[int]map[string]*dynamodb.AttributeValue
+	// segments of a previous scan that were read to the end are skipped
+	CompletedSegments map[int]bool
+	// called after every handled page with the key to continue the segment from, a nil key means the segment is done
+	OnSegmentProgress func(segment int, lastKey map[string]*dynamodb.AttributeValue)
+}
+
+type DDBFilterOperator string
+
+const (
+	DDBFilterContains   DDBFilterOperator = "contains"
+	DDBFilterBeginsWith DDBFilterOperator = "begins_with"
+	DDBFilterEquals     DDBFilterOperator = "="
+)
+
+// DDBFilter a single attribute condition compiled into a FilterExpression, values are compared as strings (case sensitive)
+type DDBFilter struct {
+	Attribute string
+	Operator  DDBFilterOperator
+	Value     string
+}
+
+func (f *DDBFilter) apply(in *dynamodb.ScanInput) {
+	expr := fmt.Sprintf("%s(#attr, :val)", f.Operator)
+	if f.Operator == DDBFilterEquals {
+		expr = "#attr = :val"
+	}
+	in.FilterExpression = aws.String(expr)
+	in.ExpressionAttributeNames = map[string]*string{"#attr": aws.String(f.Attribute)}
+	in.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":val": {S: aws.String(f.Value)}}
 }
 
 type DDBClient struct {
@@ -132,15 +250,178 @@ func (ddb *DDBClient) client() *dynamodb.DynamoDB {
 }
 
 func (ddb *DDBClient) ScanTable(name string, pageHandler DDBAttributesHandler) error {
-	c := ddb.client()
-	err := c.ScanPages(
-		&dynamodb.ScanInput{
+	return ddb.ScanTableWithOptions(name, &DDBScanOptions{}, pageHandler)
+}
+
+// ScanTableWithOptions with multiple segments the page handler is called concurrently, returning false stops all segments
+func (ddb *DDBClient) ScanTableWithOptions(name string, opts *DDBScanOptions, pageHandler DDBAttributesHandler) error {
+	segments := 1
+	if opts != nil && opts.Segments > 1 {
+		segments = opts.Segments
+	}
+	var budget *DDBCapacityBudget
+	if opts != nil {
+		budget = opts.Budget
+	}
+	if budget != nil && budget.Exhausted() {
+		log.WithField("table", name).Debug("skipping scan, read capacity budget exhausted")
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, segments)
+	for segment := 0; segment < segments; segment++ {
+		if opts != nil && opts.CompletedSegments[segment] {
+			continue
+		}
+		in := &dynamodb.ScanInput{
 			TableName: aws.String(name),
+		}
+		if opts != nil && opts.StartKeys[segment] != nil {
+			in.ExclusiveStartKey = opts.StartKeys[segment]
+		}
+		if opts != nil && opts.Filter != nil {
+			opts.Filter.apply(in)
+		}
+		if budget != nil {
+			in.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+			if limit := budget.PageLimit(); limit > 0 {
+				in.Limit = aws.Int64(limit)
+			}
+		}
+		if segments > 1 {
+			in.Segment = aws.Int64(int64(segment))
+			in.TotalSegments = aws.Int64(int64(segments))
+		}
+		wg.Add(1)
+		go func(segment int, in *dynamodb.ScanInput) {
+			defer wg.Done()
+			// throttle the first page, following pages wait in the page handler
+			if budget != nil && budget.Wait(ctx) != nil {
+				return
+			}
+			err := ddb.client().ScanPagesWithContext(ctx, in, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+				if ctx.Err() != nil {
+					return false
+				}
+				if budget != nil && page.ConsumedCapacity != nil {
+					budget.Consume(name, aws.Float64Value(page.ConsumedCapacity.CapacityUnits))
+				}
+				if !pageHandler(page.Items) {
+					cancel()
+					return false
+				}
+				if opts != nil && opts.OnSegmentProgress != nil {
+					opts.OnSegmentProgress(segment, page.LastEvaluatedKey)
+				}
+				if budget == nil {
+					return true
+				}
+				if budget.Exhausted() {
+					log.WithField("table", name).Debug("stopping scan, read capacity budget exhausted")
+					cancel()
+					return false
+				}
+				// throttle before the next page is fetched
+				return budget.Wait(ctx) == nil
+			})
+			// a canceled request is the result of another segment stopping the scan
+			if err != nil && ctx.Err() == nil {
+				errs[segment] = fmt.Errorf("failed scanning table %s segment %d %s", name, segment, err.Error())
+			}
+		}(segment, in)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetItem returns nil if the item does not exist
+func (ddb *DDBClient) GetItem(table string, key map[string]*dynamodb.AttributeValue, budget *DDBCapacityBudget) (map[string]*dynamodb.AttributeValue, error) {
+	in := &dynamodb.GetItemInput{
+		TableName: aws.String(table),
+		Key:       key,
+	}
+	if budget != nil {
+		if err := budget.Wait(context.Background()); err != nil {
+			return nil, err
+		}
+		in.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+	}
+	out, err := ddb.client().GetItem(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting item from table %s %s", table, err.Error())
+	}
+	if budget != nil && out.ConsumedCapacity != nil {
+		budget.Consume(table, aws.Float64Value(out.ConsumedCapacity.CapacityUnits))
+	}
+	if len(out.Item) == 0 {
+		return nil, nil
+	}
+	return out.Item, nil
+}
+
+func (ddb *DDBClient) QueryTable(cond *DDBKeyCondition, pageHandler DDBAttributesHandler) error {
+	in := &dynamodb.QueryInput{
+		TableName:              aws.String(cond.Table),
+		KeyConditionExpression: aws.String("#pk = :pk"),
+		ExpressionAttributeNames: map[string]*string{
+			"#pk": aws.String(cond.Index.HashKey.Name),
 		},
-		func(page *dynamodb.ScanOutput, lastPage bool) bool {
-			return pageHandler(page.Items)
-		})
-	return err
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":pk": cond.HashValue,
+		},
+	}
+	if cond.Index.IndexName != "" {
+		in.IndexName = aws.String(cond.Index.IndexName)
+	}
+	if cond.RangeValue != nil && cond.Index.RangeKey != nil {
+		expr := "#pk = :pk AND #sk = :sk"
+		if cond.RangeBeginsWith {
+			expr = "#pk = :pk AND begins_with(#sk, :sk)"
+		}
+		in.KeyConditionExpression = aws.String(expr)
+		in.ExpressionAttributeNames["#sk"] = aws.String(cond.Index.RangeKey.Name)
+		in.ExpressionAttributeValues[":sk"] = cond.RangeValue
+	}
+	budget := cond.Budget
+	if budget != nil {
+		in.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+		if limit := budget.PageLimit(); limit > 0 {
+			in.Limit = aws.Int64(limit)
+		}
+		if err := budget.Wait(context.Background()); err != nil {
+			return err
+		}
+	}
+	err := ddb.client().QueryPages(in, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		if budget != nil && page.ConsumedCapacity != nil {
+			budget.Consume(cond.Table, aws.Float64Value(page.ConsumedCapacity.CapacityUnits))
+		}
+		if !pageHandler(page.Items) {
+			return false
+		}
+		if budget == nil || lastPage {
+			return true
+		}
+		if budget.Exhausted() {
+			log.WithField("table", cond.Table).Debug("stopping query, read capacity budget exhausted")
+			return false
+		}
+		// throttle before the next page is fetched
+		return budget.Wait(context.Background()) == nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed querying table %s index %s %s", cond.Table, cond.Index.IndexName, err.Error())
+	}
+	return nil
 }
 
 func (ddb *DDBClient) DescribeTable(name string, isGlobal bool) (DDBTableDescriber, error) {
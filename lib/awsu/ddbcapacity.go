@@ -0,0 +1,109 @@
+package awsu
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DDBCapacityBudget tracks consumed read capacity per table and throttles page fetches,
+// it is shared by all tables and segments of a search. zero limits only track consumption
+type DDBCapacityBudget struct {
+	// max read capacity units consumed per second across all scans
+	MaxRCUPerSecond float64
+	// scans stop once this many read capacity units were consumed
+	MaxTotalRCU float64
+
+	mu         sync.Mutex
+	consumed   map[string]float64
+	total      float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+func NewDDBCapacityBudget(maxRCUPerSecond, maxTotalRCU float64) *DDBCapacityBudget {
+	return &DDBCapacityBudget{
+		MaxRCUPerSecond: maxRCUPerSecond,
+		MaxTotalRCU:     maxTotalRCU,
+		consumed:        map[string]float64{},
+		// allow a second worth of capacity before throttling
+		tokens:     maxRCUPerSecond,
+		lastRefill: time.Now(),
+	}
+}
+
+// eventually consistent reads cost 0.5 RCU per 4KB, pages are sized for items of up to 4KB
+const ddbItemsPerRCU = 2
+
+// PageLimit items per request so a single page costs about a second of the rate budget, 0 means no limit
+func (b *DDBCapacityBudget) PageLimit() int64 {
+	if b.MaxRCUPerSecond <= 0 {
+		return 0
+	}
+	limit := int64(b.MaxRCUPerSecond * ddbItemsPerRCU)
+	if limit < 1 {
+		return 1
+	}
+	return limit
+}
+
+// Consume records the capacity a page consumed
+func (b *DDBCapacityBudget) Consume(table string, units float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consumed[table] += units
+	b.total += units
+	b.tokens -= units
+}
+
+// Exhausted true once the total budget is consumed
+func (b *DDBCapacityBudget) Exhausted() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.MaxTotalRCU > 0 && b.total >= b.MaxTotalRCU
+}
+
+// Wait blocks until the rate budget allows another request, called before every request.
+// a request cost is only known after it is read so the budget may go negative and the next request waits for it to refill
+func (b *DDBCapacityBudget) Wait(ctx context.Context) error {
+	if b.MaxRCUPerSecond <= 0 {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.lastRefill).Seconds() * b.MaxRCUPerSecond
+		if b.tokens > b.MaxRCUPerSecond {
+			b.tokens = b.MaxRCUPerSecond
+		}
+		b.lastRefill = now
+		missing := -b.tokens
+		b.mu.Unlock()
+
+		if missing <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(missing / b.MaxRCUPerSecond * float64(time.Second))):
+		}
+	}
+}
+
+// ConsumedByTable read capacity units consumed per table
+func (b *DDBCapacityBudget) ConsumedByTable() map[string]float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	consumed := make(map[string]float64, len(b.consumed))
+	for t, units := range b.consumed {
+		consumed[t] = units
+	}
+	return consumed
+}
+
+func (b *DDBCapacityBudget) TotalConsumed() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.total
+}
@@ -0,0 +1,58 @@
+package awsu
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestDDBCapacityBudgetRefill(t *testing.T) {
+	b := NewDDBCapacityBudget(100, 0)
+
+	// a second worth of capacity is available up front
+	start := time.Now()
+	assert.Equal(t, b.Wait(context.Background()), nil)
+	assert.Equal(t, time.Since(start) < 50*time.Millisecond, true)
+
+	// overspending waits until the budget refills
+	b.Consume("orders", 120)
+	start = time.Now()
+	assert.Equal(t, b.Wait(context.Background()), nil)
+	elapsed := time.Since(start)
+	assert.Equal(t, elapsed >= 150*time.Millisecond, true)
+	assert.Equal(t, elapsed < time.Second, true)
+}
+
+func TestDDBCapacityBudgetExhausted(t *testing.T) {
+	b := NewDDBCapacityBudget(0, 10)
+	b.Consume("orders", 4)
+	assert.Equal(t, b.Exhausted(), false)
+	b.Consume("users", 6)
+	assert.Equal(t, b.Exhausted(), true)
+	assert.Equal(t, b.TotalConsumed(), 10.0)
+	assert.Equal(t, b.ConsumedByTable(), map[string]float64{"orders": 4, "users": 6})
+
+	// without a total limit consumption is only tracked
+	unlimited := NewDDBCapacityBudget(0, 0)
+	unlimited.Consume("orders", 1000)
+	assert.Equal(t, unlimited.Exhausted(), false)
+	assert.Equal(t, unlimited.Wait(context.Background()), nil)
+}
+
+func TestDDBCapacityBudgetWaitCanceled(t *testing.T) {
+	b := NewDDBCapacityBudget(1, 0)
+	b.Consume("orders", 100)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Equal(t, b.Wait(ctx), context.DeadlineExceeded)
+	assert.Equal(t, time.Since(start) < time.Second, true)
+}
+
+func TestDDBCapacityBudgetPageLimit(t *testing.T) {
+	assert.Equal(t, NewDDBCapacityBudget(0, 100).PageLimit(), int64(0))
+	assert.Equal(t, NewDDBCapacityBudget(50, 0).PageLimit(), int64(100))
+	assert.Equal(t, NewDDBCapacityBudget(0.2, 0).PageLimit(), int64(1))
+}
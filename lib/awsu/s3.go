@@ -2,16 +2,29 @@ package awsu
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/aws-sdk-go/aws"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	log "github.com/sirupsen/logrus"
 )
 
+const bucketRegionHeader = "X-Amz-Bucket-Region"
+
 type S3API interface {
 	ListAllBuckets() ([]types.Bucket, error)
 	ListAllObjects(bucket, prefix string) ([]types.Object, error)
+	ListObjectsPages(ctx context.Context, bucket, prefix, delimiter string, fn func(page *s3.ListObjectsV2Output) bool) error
+	ListAllObjectVersions(bucket, prefix string) ([]types.ObjectVersion, []types.DeleteMarkerEntry, error)
+	GetObject(bucket, key string) (*s3.GetObjectOutput, error)
+	HeadObject(bucket, key string) (*s3.HeadObjectOutput, error)
+	GetObjectTags(bucket, key string) ([]types.Tag, error)
+	GetBucketRegion(bucket string) (string, error)
+	GetBucketConfig(bucket string, configType BucketConfigType) (string, error)
 }
 
 type S3Client struct {
@@ -70,6 +83,139 @@ func (s *S3Client) ListAllObjects(bucket, prefix string) ([]types.Object, error)
 	return allObjects, nil
 }
 
+// ListObjectsPages calls fn for every page of up to 1000 keys without accumulating them, fn returns false to stop paging
+func (s *S3Client) ListObjectsPages(ctx context.Context, bucket, prefix, delimiter string, fn func(page *s3.ListObjectsV2Output) bool) error {
+	in := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: 1000,
+	}
+	if delimiter != "" {
+		in.Delimiter = aws.String(delimiter)
+	}
+	paginator := s3.NewListObjectsV2Paginator(s.client(), in)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed listing objects s3://%s/%s %s", bucket, prefix, err.Error())
+		}
+		if !fn(page) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// ListAllObjectVersions returns all versions and delete markers of keys under the prefix
+func (s *S3Client) ListAllObjectVersions(bucket, prefix string) ([]types.ObjectVersion, []types.DeleteMarkerEntry, error) {
+	var versions []types.ObjectVersion
+	var deleteMarkers []types.DeleteMarkerEntry
+	in := &s3.ListObjectVersionsInput{
+		Bucket:  aws.String(bucket),
+		Prefix:  aws.String(prefix),
+		MaxKeys: 1000,
+	}
+
+	for {
+		resp, err := s.client().ListObjectVersions(context.TODO(), in)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed listing object versions %s", err.Error())
+		}
+
+		versions = append(versions, resp.Versions...)
+		deleteMarkers = append(deleteMarkers, resp.DeleteMarkers...)
+
+		if resp.IsTruncated {
+			in.KeyMarker = resp.NextKeyMarker
+			in.VersionIdMarker = resp.NextVersionIdMarker
+		} else {
+			break
+		}
+	}
+
+	return versions, deleteMarkers, nil
+}
+
+// GetObject caller is responsible to close the output Body
+func (s *S3Client) GetObject(bucket, key string) (*s3.GetObjectOutput, error) {
+	out, err := s.client().GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed getting object s3://%s/%s %s", bucket, key, err.Error())
+	}
+	return out, nil
+}
+
+func (s *S3Client) HeadObject(bucket, key string) (*s3.HeadObjectOutput, error) {
+	out, err := s.client().HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed head object s3://%s/%s %s", bucket, key, err.Error())
+	}
+	return out, nil
+}
+
+func (s *S3Client) GetObjectTags(bucket, key string) ([]types.Tag, error) {
+	out, err := s.client().GetObjectTagging(context.TODO(), &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed getting object tags s3://%s/%s %s", bucket, key, err.Error())
+	}
+	return out.TagSet, nil
+}
+
+// GetBucketRegion resolves via GetBucketLocation and falls back to the HeadBucket x-amz-bucket-region header
+func (s *S3Client) GetBucketRegion(bucket string) (string, error) {
+	loc, err := s.client().GetBucketLocation(context.TODO(), &s3.GetBucketLocationInput{
+		Bucket: aws.String(bucket),
+	})
+	if err == nil {
+		return normalizeBucketLocation(loc.LocationConstraint), nil
+	}
+	log.WithError(err).WithField("bucket", bucket).Debug("failed getting bucket location, trying head bucket")
+
+	out, headErr := s.client().HeadBucket(context.TODO(), &s3.HeadBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	// on a redirect (301) the region header is still returned as part of the error response
+	var respErr *smithyhttp.ResponseError
+	if headErr != nil && errors.As(headErr, &respErr) && respErr.Response != nil {
+		if region := respErr.Response.Header.Get(bucketRegionHeader); region != "" {
+			return region, nil
+		}
+	}
+	if headErr == nil {
+		if raw, ok := awsmiddleware.GetRawResponse(out.ResultMetadata).(*smithyhttp.Response); ok {
+			if region := raw.Header.Get(bucketRegionHeader); region != "" {
+				return region, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("failed resolving bucket %s region %s", bucket, err.Error())
+}
+
+// us-east-1 has an empty location constraint and EU is the legacy eu-west-1
+func normalizeBucketLocation(loc types.BucketLocationConstraint) string {
+	switch loc {
+	case "":
+		return "us-east-1"
+	case types.BucketLocationConstraintEu:
+		return "eu-west-1"
+	default:
+		return string(loc)
+	}
+}
+
 func GenerateS3WebURL(bucket, region, prefix string) string {
 	return fmt.Sprintf("https://s3.console.aws.amazon.com/s3/object/%s?region=%s&prefix=%s", bucket, region, prefix)
 }
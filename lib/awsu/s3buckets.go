@@ -0,0 +1,119 @@
+package awsu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/smithy-go"
+)
+
+type BucketConfigType string
+
+const (
+	BucketPolicyConfig            BucketConfigType = "policy"
+	BucketACLConfig               BucketConfigType = "acl"
+	BucketPublicAccessBlockConfig BucketConfigType = "public-access-block"
+	BucketEncryptionConfig        BucketConfigType = "encryption"
+	BucketVersioningConfig        BucketConfigType = "versioning"
+	BucketLifecycleConfig         BucketConfigType = "lifecycle"
+	BucketTagsConfig              BucketConfigType = "tags"
+	// the document returned for configurations that are not set on the bucket i.e no default encryption
+	BucketConfigNotConfigured = "not-configured"
+)
+
+var AllBucketConfigTypes = []BucketConfigType{
+	BucketPolicyConfig,
+	BucketACLConfig,
+	BucketPublicAccessBlockConfig,
+	BucketEncryptionConfig,
+	BucketVersioningConfig,
+	BucketLifecycleConfig,
+	BucketTagsConfig,
+}
+
+// error codes S3 returns when a configuration was never set on the bucket
+var bucketConfigNotFoundCodes = map[string]bool{
+	"NoSuchBucketPolicy":                             true,
+	"NoSuchPublicAccessBlockConfiguration":           true,
+	"ServerSideEncryptionConfigurationNotFoundError": true,
+	"NoSuchLifecycleConfiguration":                   true,
+	"NoSuchTagSet":                                   true,
+}
+
+func isBucketConfigNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && bucketConfigNotFoundCodes[apiErr.ErrorCode()]
+}
+
+// GetBucketConfig returns the configuration as a json document or BucketConfigNotConfigured
+func (s *S3Client) GetBucketConfig(bucket string, configType BucketConfigType) (string, error) {
+	ctx := context.TODO()
+	b := aws.String(bucket)
+	var doc any
+	var err error
+
+	switch configType {
+	case BucketPolicyConfig:
+		var out *s3.GetBucketPolicyOutput
+		if out, err = s.client().GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{Bucket: b}); err == nil {
+			// the policy is already a json document
+			return aws.StringValue(out.Policy), nil
+		}
+	case BucketACLConfig:
+		var out *s3.GetBucketAclOutput
+		if out, err = s.client().GetBucketAcl(ctx, &s3.GetBucketAclInput{Bucket: b}); err == nil {
+			doc = map[string]any{"Owner": out.Owner, "Grants": out.Grants}
+		}
+	case BucketPublicAccessBlockConfig:
+		var out *s3.GetPublicAccessBlockOutput
+		if out, err = s.client().GetPublicAccessBlock(ctx, &s3.GetPublicAccessBlockInput{Bucket: b}); err == nil {
+			doc = out.PublicAccessBlockConfiguration
+		}
+	case BucketEncryptionConfig:
+		var out *s3.GetBucketEncryptionOutput
+		if out, err = s.client().GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{Bucket: b}); err == nil {
+			doc = out.ServerSideEncryptionConfiguration
+		}
+	case BucketVersioningConfig:
+		var out *s3.GetBucketVersioningOutput
+		if out, err = s.client().GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{Bucket: b}); err == nil {
+			// never enabled versioning has an empty status
+			if out.Status == "" {
+				return BucketConfigNotConfigured, nil
+			}
+			doc = map[string]any{"Status": out.Status, "MFADelete": out.MFADelete}
+		}
+	case BucketLifecycleConfig:
+		var out *s3.GetBucketLifecycleConfigurationOutput
+		if out, err = s.client().GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{Bucket: b}); err == nil {
+			doc = out.Rules
+		}
+	case BucketTagsConfig:
+		var out *s3.GetBucketTaggingOutput
+		if out, err = s.client().GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: b}); err == nil {
+			doc = out.TagSet
+		}
+	default:
+		return "", fmt.Errorf("unknown bucket configuration %s", configType)
+	}
+
+	if err != nil {
+		if isBucketConfigNotFound(err) {
+			return BucketConfigNotConfigured, nil
+		}
+		return "", fmt.Errorf("failed getting bucket %s %s %s", bucket, configType, err.Error())
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed marshal bucket %s %s %s", bucket, configType, err.Error())
+	}
+	return string(raw), nil
+}
+
+func GenerateS3BucketWebURL(bucket, region string) string {
+	return fmt.Sprintf("https://s3.console.aws.amazon.com/s3/buckets/%s?region=%s&tab=permissions", bucket, region)
+}
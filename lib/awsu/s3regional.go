@@ -0,0 +1,135 @@
+package awsu
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// S3RegionalClient implements S3API routing every bucket request to a client in the bucket region
+// clients are created lazily per region and bucket regions are cached
+type S3RegionalClient struct {
+	auth          *AuthInput
+	defaultClient S3API
+	mu            sync.Mutex
+	clients       map[string]S3API
+	bucketRegions map[string]string
+}
+
+func NewS3RegionalClient(in *AuthInput) (S3API, error) {
+	c, err := NewS3(in)
+	if err != nil {
+		return nil, err
+	}
+	defaultClient := NewS3Client(c)
+	return &S3RegionalClient{
+		auth:          in,
+		defaultClient: defaultClient,
+		clients:       map[string]S3API{in.EffectiveRegion: defaultClient},
+		bucketRegions: map[string]string{},
+	}, nil
+}
+
+func (r *S3RegionalClient) regionClient(region string) (S3API, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, exist := r.clients[region]; exist {
+		return c, nil
+	}
+	log.WithField("region", region).Debug("creating s3 client for region")
+	c, err := NewS3ForRegion(r.auth, region)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating s3 client for region %s %s", region, err.Error())
+	}
+	r.clients[region] = NewS3Client(c)
+	return r.clients[region], nil
+}
+
+func (r *S3RegionalClient) bucketClient(bucket string) (S3API, error) {
+	region, err := r.GetBucketRegion(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return r.regionClient(region)
+}
+
+func (r *S3RegionalClient) GetBucketRegion(bucket string) (string, error) {
+	r.mu.Lock()
+	region, exist := r.bucketRegions[bucket]
+	r.mu.Unlock()
+	if exist {
+		return region, nil
+	}
+	region, err := r.defaultClient.GetBucketRegion(bucket)
+	if err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	r.bucketRegions[bucket] = region
+	r.mu.Unlock()
+	return region, nil
+}
+
+func (r *S3RegionalClient) ListAllBuckets() ([]types.Bucket, error) {
+	return r.defaultClient.ListAllBuckets()
+}
+
+func (r *S3RegionalClient) ListAllObjects(bucket, prefix string) ([]types.Object, error) {
+	c, err := r.bucketClient(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return c.ListAllObjects(bucket, prefix)
+}
+
+func (r *S3RegionalClient) ListObjectsPages(ctx context.Context, bucket, prefix, delimiter string, fn func(page *s3.ListObjectsV2Output) bool) error {
+	c, err := r.bucketClient(bucket)
+	if err != nil {
+		return err
+	}
+	return c.ListObjectsPages(ctx, bucket, prefix, delimiter, fn)
+}
+
+func (r *S3RegionalClient) ListAllObjectVersions(bucket, prefix string) ([]types.ObjectVersion, []types.DeleteMarkerEntry, error) {
+	c, err := r.bucketClient(bucket)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.ListAllObjectVersions(bucket, prefix)
+}
+
+func (r *S3RegionalClient) GetObject(bucket, key string) (*s3.GetObjectOutput, error) {
+	c, err := r.bucketClient(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetObject(bucket, key)
+}
+
+func (r *S3RegionalClient) HeadObject(bucket, key string) (*s3.HeadObjectOutput, error) {
+	c, err := r.bucketClient(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return c.HeadObject(bucket, key)
+}
+
+func (r *S3RegionalClient) GetObjectTags(bucket, key string) ([]types.Tag, error) {
+	c, err := r.bucketClient(bucket)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetObjectTags(bucket, key)
+}
+
+func (r *S3RegionalClient) GetBucketConfig(bucket string, configType BucketConfigType) (string, error) {
+	c, err := r.bucketClient(bucket)
+	if err != nil {
+		return "", err
+	}
+	return c.GetBucketConfig(bucket, configType)
+}
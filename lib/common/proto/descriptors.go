@@ -0,0 +1,195 @@
+package proto
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Descriptors message types loaded from .proto sources or compiled FileDescriptorSet files (protoc --descriptor_set_out)
+type Descriptors struct {
+	files       *protoregistry.Files
+	fingerprint string
+}
+
+// NewDescriptors loads every path, files ending with .proto are compiled with importPaths anything else is read as a FileDescriptorSet
+func NewDescriptors(paths []string, importPaths []string) (*Descriptors, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	var sources []string
+	for _, p := range paths {
+		if filepath.Ext(p) == ".proto" {
+			sources = append(sources, p)
+			continue
+		}
+		fds, err := readDescriptorSet(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fds.File {
+			if !seen[f.GetName()] {
+				seen[f.GetName()] = true
+				set.File = append(set.File, f)
+			}
+		}
+	}
+	if len(sources) > 0 {
+		compiled, err := compileSources(sources, importPaths)
+		if err != nil {
+			return nil, err
+		}
+		for _, fd := range compiled {
+			appendWithDependencies(fd, set, seen)
+		}
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed building proto descriptors %s", err.Error())
+	}
+	fingerprint, err := fingerprintSet(set)
+	if err != nil {
+		return nil, err
+	}
+	return &Descriptors{files: files, fingerprint: fingerprint}, nil
+}
+
+// fingerprintSet a hash of the files, equal for the same message definitions regardless of the load order
+func fingerprintSet(set *descriptorpb.FileDescriptorSet) (string, error) {
+	sorted := &descriptorpb.FileDescriptorSet{File: append([]*descriptorpb.FileDescriptorProto{}, set.File...)}
+	sort.Slice(sorted.File, func(a, b int) bool {
+		return sorted.File[a].GetName() < sorted.File[b].GetName()
+	})
+	raw, err := gproto.MarshalOptions{Deterministic: true}.Marshal(sorted)
+	if err != nil {
+		return "", fmt.Errorf("failed marshal proto descriptors %s", err.Error())
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Fingerprint identifies the loaded message definitions, i.e to check a checkpoint is resumed with the same descriptors
+func (d *Descriptors) Fingerprint() string {
+	return d.fingerprint
+}
+
+func readDescriptorSet(path string) (*descriptorpb.FileDescriptorSet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading descriptor set %s %s", path, err.Error())
+	}
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err := gproto.Unmarshal(raw, fds); err != nil {
+		return nil, fmt.Errorf("failed parsing descriptor set %s %s", path, err.Error())
+	}
+	return fds, nil
+}
+
+// compileSources sources are relative to one of the import paths or to the working directory
+func compileSources(sources []string, importPaths []string) ([]protoreflect.FileDescriptor, error) {
+	resolvePaths := append([]string{}, importPaths...)
+	var names []string
+	for _, src := range sources {
+		name, underImportPath := relativeToImportPaths(src, importPaths)
+		if !underImportPath && len(importPaths) > 0 {
+			// i.e an absolute path or a path not under any of the import paths
+			resolvePaths = append(resolvePaths, ".")
+		}
+		names = append(names, name)
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: resolvePaths}),
+	}
+	compiled, err := compiler.Compile(context.Background(), names...)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing proto files %s", err.Error())
+	}
+	var files []protoreflect.FileDescriptor
+	for _, f := range compiled {
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// relativeToImportPaths ./protos/order.proto with import path ./protos is order.proto
+func relativeToImportPaths(src string, importPaths []string) (string, bool) {
+	if _, err := os.Stat(src); err != nil {
+		// already relative to an import path
+		return src, true
+	}
+	for _, ip := range importPaths {
+		rel, err := filepath.Rel(ip, src)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel), true
+		}
+	}
+	return src, false
+}
+
+// appendWithDependencies dependencies are added before the file that imports them
+func appendWithDependencies(fd protoreflect.FileDescriptor, set *descriptorpb.FileDescriptorSet, seen map[string]bool) {
+	if seen[fd.Path()] {
+		return
+	}
+	seen[fd.Path()] = true
+	imports := fd.Imports()
+	for idx := 0; idx < imports.Len(); idx++ {
+		appendWithDependencies(imports.Get(idx).FileDescriptor, set, seen)
+	}
+	set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+}
+
+func (d *Descriptors) findMessage(messageName string) (protoreflect.MessageDescriptor, error) {
+	found, err := d.files.FindDescriptorByName(protoreflect.FullName(messageName))
+	if err != nil {
+		return nil, fmt.Errorf("message type %s not found %s", messageName, err.Error())
+	}
+	md, ok := found.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message type", messageName)
+	}
+	return md, nil
+}
+
+// HasMessage true if the full message name i.e shop.v1.Order is known
+func (d *Descriptors) HasMessage(messageName string) bool {
+	_, err := d.findMessage(messageName)
+	return err == nil
+}
+
+// DecodeToJSON decodes the payload as messageName into json with the proto field names
+func (d *Descriptors) DecodeToJSON(messageName string, payload []byte) (string, error) {
+	md, err := d.findMessage(messageName)
+	if err != nil {
+		return "", err
+	}
+	msg := dynamicpb.NewMessage(md)
+	if err := gproto.Unmarshal(payload, msg); err != nil {
+		return "", fmt.Errorf("failed decoding %s %s", messageName, err.Error())
+	}
+	raw, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("failed marshal %s to json %s", messageName, err.Error())
+	}
+	// protojson output spacing is randomized on purpose, compact it for stable output
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return string(raw), nil
+	}
+	return compact.String(), nil
+}
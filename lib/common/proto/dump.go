@@ -0,0 +1,95 @@
+package proto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Dump renders wire format without a schema in a protoscope like text: field numbers with their values,
+// length delimited fields are shown as nested messages when they parse as one, else as quoted text or hex.
+// returns false if b is not a valid protobuf payload
+func Dump(b []byte) (string, bool) {
+	var res strings.Builder
+	if len(b) == 0 || !dumpMessage(b, &res) {
+		return "", false
+	}
+	return res.String(), true
+}
+
+func dumpMessage(b []byte, res *strings.Builder) bool {
+	first := true
+	for len(b) > 0 {
+		num, typ, taglen := protowire.ConsumeTag(b)
+		if taglen < 0 {
+			return false
+		}
+		vlen := protowire.ConsumeFieldValue(num, typ, b[taglen:])
+		if vlen < 0 {
+			return false
+		}
+		val := b[taglen : taglen+vlen]
+		if !first {
+			res.WriteString(" ")
+		}
+		first = false
+		res.WriteString(fmt.Sprintf("%d: ", num))
+
+		switch typ {
+		case protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(val)
+			res.WriteString(strconv.FormatUint(v, 10))
+		case protowire.Fixed32Type:
+			v, _ := protowire.ConsumeFixed32(val)
+			res.WriteString(fmt.Sprintf("%di32", v))
+		case protowire.Fixed64Type:
+			v, _ := protowire.ConsumeFixed64(val)
+			res.WriteString(fmt.Sprintf("%di64", v))
+		case protowire.BytesType:
+			v, _ := protowire.ConsumeBytes(val)
+			dumpBytes(v, res)
+		case protowire.StartGroupType:
+			v, _ := protowire.ConsumeGroup(num, val)
+			res.WriteString("!{")
+			if !dumpMessage(v, res) {
+				return false
+			}
+			res.WriteString("}")
+		default:
+			return false
+		}
+		b = b[taglen+vlen:]
+	}
+	return true
+}
+
+func dumpBytes(v []byte, res *strings.Builder) {
+	// readable text is more likely than a message that happens to parse
+	if IsPrintableText(v) {
+		res.WriteString(strconv.Quote(string(v)))
+		return
+	}
+	var nested strings.Builder
+	if len(v) > 0 && dumpMessage(v, &nested) {
+		res.WriteString("{" + nested.String() + "}")
+		return
+	}
+	res.WriteString(fmt.Sprintf("`%x`", v))
+}
+
+// IsPrintableText true if b is valid utf8 without control characters except whitespace
+func IsPrintableText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
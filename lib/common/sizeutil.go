@@ -0,0 +1,50 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = []struct {
+	Suffix string
+	Bytes  int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize converts human readable sizes "10MB", "512kb", "1.5GB" or plain bytes "1024" to bytes
+func ParseByteSize(sizeStr string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(sizeStr))
+	if s == "" {
+		return 0, nil
+	}
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.Suffix) {
+			num, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, u.Suffix)), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid size %s - %s", sizeStr, err.Error())
+			}
+			return int64(num * float64(u.Bytes)), nil
+		}
+	}
+	num, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %s - %s", sizeStr, err.Error())
+	}
+	return num, nil
+}
+
+// FmtByteSize formats bytes to a human readable size i.e 1.5GB
+func FmtByteSize(bytes int64) string {
+	for _, u := range sizeUnits {
+		if bytes >= u.Bytes && u.Bytes > 1 {
+			return fmt.Sprintf("%.1f%s", float64(bytes)/float64(u.Bytes), u.Suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", bytes)
+}
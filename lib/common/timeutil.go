@@ -82,3 +82,27 @@ func Get_ISO_UTC_Timeoffset() string {
 	}
 	return fmt.Sprintf("UTC%s%d", offset, hourOffset)
 }
+
+// ParseHumanDuration extends time.ParseDuration with days, weeks and years i.e "7d", "2w", "1y"
+func ParseHumanDuration(durationStr string) (time.Duration, error) {
+	s := strings.TrimSpace(durationStr)
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if strings.HasSuffix(s, suffix) {
+			num, err := strconv.ParseFloat(strings.TrimSuffix(s, suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed parse duration %s - %s", durationStr, err.Error())
+			}
+			return time.Duration(num * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed parse duration %s - %s", durationStr, err.Error())
+	}
+	return d, nil
+}
@@ -1,19 +1,56 @@
 package consul
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	c "github.com/hashicorp/consul/api"
 )
 
 type Client interface {
 	List(prefix string) (c.KVPairs, error)
+	Get(key string) (*c.KVPair, error)
+	Put(pair *c.KVPair) error
+	WatchList(ctx context.Context, prefix string, waitIndex uint64, waitTime time.Duration) (c.KVPairs, uint64, error)
+	ListServices() (map[string][]string, error)
+	ListServiceInstances(service string) ([]*c.CatalogService, error)
+	ListNodes() ([]*c.Node, error)
+	ListHealthChecks() (c.HealthChecks, error)
 	GetSchemeType() string
 	GetConsulAddr() string
 	GetConsulUIBaseAddr() (string, error)
 	GetCurrentDatacenter() (string, error)
 	ListDatacenters() ([]string, error)
+	WithDatacenter(dc string) (Client, error)
+	WithNamespace(ns string) (Client, error)
+	ListNamespaces() ([]string, error)
+	GetNamespace() string
+	GetPartition() string
+}
+
+// ClientConfig holds the connection details, empty values fallback to the standard CONSUL_* environment variables
+type ClientConfig struct {
+	Address    string
+	Datacenter string
+	// ACL token, takes precedence over TokenFile
+	Token     string
+	TokenFile string
+	// Consul Enterprise namespace and admin partition
+	Namespace string
+	Partition string
+	TLS       TLSConfig
+}
+
+type TLSConfig struct {
+	CAFile             string
+	CAPath             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
 }
 
 type ConsulClient struct {
@@ -22,9 +59,35 @@ type ConsulClient struct {
 }
 
 func NewClient(address string, datacenter string) (Client, error) {
-	config := c.Config{
+	return NewClientFromConfig(&ClientConfig{
 		Address:    address,
 		Datacenter: datacenter,
+	})
+}
+
+func NewClientFromConfig(conf *ClientConfig) (Client, error) {
+	config := c.Config{
+		Address:    conf.Address,
+		Datacenter: conf.Datacenter,
+		Token:      conf.Token,
+		Namespace:  conf.Namespace,
+		Partition:  conf.Partition,
+		TLSConfig: c.TLSConfig{
+			Address:            conf.TLS.ServerName,
+			CAFile:             conf.TLS.CAFile,
+			CAPath:             conf.TLS.CAPath,
+			CertFile:           conf.TLS.CertFile,
+			KeyFile:            conf.TLS.KeyFile,
+			InsecureSkipVerify: conf.TLS.InsecureSkipVerify,
+		},
+	}
+	// token file is always preferred by the consul api so only set it if no explicit token
+	if conf.Token == "" {
+		config.TokenFile = conf.TokenFile
+		// the consul api only reads CONSUL_HTTP_TOKEN_FILE in DefaultConfig which is not used here
+		if config.TokenFile == "" {
+			config.TokenFile = os.Getenv(c.HTTPTokenFileEnvName)
+		}
 	}
 	client, err := c.NewClient(&config)
 	if err != nil {
@@ -46,6 +109,67 @@ func (client *ConsulClient) List(prefix string) (c.KVPairs, error) {
 	return pairs, nil
 }
 
+// Get returns nil if the key does not exist
+func (client *ConsulClient) Get(key string) (*c.KVPair, error) {
+	pair, _, err := client.client.KV().Get(key, &c.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (client *ConsulClient) Put(pair *c.KVPair) error {
+	_, err := client.client.KV().Put(pair, &c.WriteOptions{})
+	return err
+}
+
+// WatchList is a blocking query on the prefix, returns once the KV index is greater than waitIndex or waitTime passed
+func (client *ConsulClient) WatchList(ctx context.Context, prefix string, waitIndex uint64, waitTime time.Duration) (c.KVPairs, uint64, error) {
+	query := &c.QueryOptions{
+		WaitIndex: waitIndex,
+		WaitTime:  waitTime,
+	}
+	pairs, meta, err := client.client.KV().List(prefix, query.WithContext(ctx))
+	if err != nil {
+		return nil, 0, err
+	}
+	return pairs, meta.LastIndex, nil
+}
+
+// ListServices returns all service names in the catalog mapped to their tags
+func (client *ConsulClient) ListServices() (map[string][]string, error) {
+	services, _, err := client.client.Catalog().Services(&c.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func (client *ConsulClient) ListServiceInstances(service string) ([]*c.CatalogService, error) {
+	instances, _, err := client.client.Catalog().Service(service, "", &c.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+func (client *ConsulClient) ListNodes() ([]*c.Node, error) {
+	nodes, _, err := client.client.Catalog().Nodes(&c.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// ListHealthChecks returns the health checks in any state
+func (client *ConsulClient) ListHealthChecks() (c.HealthChecks, error) {
+	checks, _, err := client.client.Health().State(c.HealthAny, &c.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
 // http or https scheme type
 func (client *ConsulClient) GetSchemeType() string {
 	return client.config.Scheme
@@ -70,6 +194,14 @@ func (client *ConsulClient) GetConsulUIBaseAddr() (string, error) {
 
 	uiBaseUrl := fmt.Sprintf("%s/ui", addr)
 
+	// enterprise ui routes are prefixed with _<partition> and ~<namespace>
+	if p := client.GetPartition(); p != "" && p != "default" {
+		uiBaseUrl = fmt.Sprintf("%s/_%s", uiBaseUrl, p)
+	}
+	if ns := client.GetNamespace(); ns != "" && ns != "default" {
+		uiBaseUrl = fmt.Sprintf("%s/~%s", uiBaseUrl, ns)
+	}
+
 	if dc != "" {
 		uiBaseUrl = fmt.Sprintf("%s/%s", uiBaseUrl, dc)
 	}
@@ -110,6 +242,63 @@ func (client *ConsulClient) ListDatacenters() ([]string, error) {
 	return client.client.Catalog().Datacenters()
 }
 
+// WithDatacenter returns a new client sharing the same configuration but bound to another datacenter
+func (client *ConsulClient) WithDatacenter(dc string) (Client, error) {
+	config := *client.config
+	config.Datacenter = dc
+	cc, err := c.NewClient(&config)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating consul client for datacenter %s - %s", dc, err.Error())
+	}
+	return &ConsulClient{
+		client: cc,
+		config: &config,
+	}, nil
+}
+
+// WithNamespace returns a new client sharing the same configuration but bound to another namespace (Consul Enterprise)
+func (client *ConsulClient) WithNamespace(ns string) (Client, error) {
+	config := *client.config
+	config.Namespace = ns
+	cc, err := c.NewClient(&config)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating consul client for namespace %s - %s", ns, err.Error())
+	}
+	return &ConsulClient{
+		client: cc,
+		config: &config,
+	}, nil
+}
+
+// ListNamespaces returns all namespaces in the client partition (Consul Enterprise only)
+func (client *ConsulClient) ListNamespaces() ([]string, error) {
+	namespaces, _, err := client.client.Namespaces().List(&c.QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, ns := range namespaces {
+		names = append(names, ns.Name)
+	}
+	return names, nil
+}
+
+func (client *ConsulClient) GetNamespace() string {
+	return client.config.Namespace
+}
+
+func (client *ConsulClient) GetPartition() string {
+	return client.config.Partition
+}
+
 func GenerateKVWebURL(uiBaseAddress, key string) string {
 	return fmt.Sprintf("%s/kv/%s/edit", uiBaseAddress, key)
 }
+
+func GenerateServiceWebURL(uiBaseAddress, service string) string {
+	return fmt.Sprintf("%s/services/%s", uiBaseAddress, service)
+}
+
+func GenerateNodeWebURL(uiBaseAddress, node string) string {
+	return fmt.Sprintf("%s/nodes/%s", uiBaseAddress, node)
+}
@@ -0,0 +1,34 @@
+package consul
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	c "github.com/hashicorp/consul/api"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestNewClientFromConfigTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env-token")
+	flagFile := filepath.Join(dir, "flag-token")
+	assert.Equal(t, os.WriteFile(envFile, []byte("env-secret\n"), 0600), nil)
+	assert.Equal(t, os.WriteFile(flagFile, []byte("flag-secret"), 0600), nil)
+	t.Setenv(c.HTTPTokenEnvName, "")
+	t.Setenv(c.HTTPTokenFileEnvName, envFile)
+
+	cases := []struct {
+		conf     *ClientConfig
+		expected string
+	}{
+		{&ClientConfig{}, "env-secret"},
+		{&ClientConfig{TokenFile: flagFile}, "flag-secret"},
+		{&ClientConfig{Token: "explicit", TokenFile: flagFile}, "explicit"},
+	}
+	for _, tc := range cases {
+		client, err := NewClientFromConfig(tc.conf)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, client.(*ConsulClient).config.Token, tc.expected)
+	}
+}
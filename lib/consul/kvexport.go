@@ -0,0 +1,147 @@
+package consul
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	c "github.com/hashicorp/consul/api"
+)
+
+type ImportAction string
+
+const (
+	ImportCreate    ImportAction = "create"
+	ImportUpdate    ImportAction = "update"
+	ImportUnchanged ImportAction = "unchanged"
+)
+
+// ExportedKV is a single entry in the format of `consul kv export` and `consul kv import`
+type ExportedKV struct {
+	Key   string `json:"key"`
+	Flags uint64 `json:"flags"`
+	// base64 encoded
+	Value string `json:"value"`
+}
+
+func NewExportedKVs(pairs c.KVPairs) []*ExportedKV {
+	entries := []*ExportedKV{}
+	for _, p := range pairs {
+		entries = append(entries, &ExportedKV{
+			Key:   p.Key,
+			Flags: p.Flags,
+			Value: base64.StdEncoding.EncodeToString(p.Value),
+		})
+	}
+	return entries
+}
+
+func WriteExportFile(path string, entries []*ExportedKV) error {
+	data, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return fmt.Errorf("failed marshalling kv export - %s", err.Error())
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed writing kv export file %s - %s", path, err.Error())
+	}
+	return nil
+}
+
+func ReadExportFile(path string) ([]*ExportedKV, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading kv export file %s - %s", path, err.Error())
+	}
+	var entries []*ExportedKV
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed parsing kv export file %s - %s", path, err.Error())
+	}
+	return entries, nil
+}
+
+// KeyRewrite replaces the regex Pattern in keys with Replacement (supports $1 style groups)
+type KeyRewrite struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// ParseKeyRewrite parses a rule in the form of 'pattern=replacement' i.e '^prod/=staging/'
+func ParseKeyRewrite(rule string) (*KeyRewrite, error) {
+	if rule == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(rule, "=", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid rewrite rule %s must be in the form of 'pattern=replacement'", rule)
+	}
+	pattern, err := regexp.Compile(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid rewrite pattern %s - %s", parts[0], err.Error())
+	}
+	return &KeyRewrite{Pattern: pattern, Replacement: parts[1]}, nil
+}
+
+func (r *KeyRewrite) Apply(key string) string {
+	if r == nil {
+		return key
+	}
+	return r.Pattern.ReplaceAllString(key, r.Replacement)
+}
+
+type ImportPlanItem struct {
+	Action    ImportAction
+	SourceKey string
+	// the key after rewrite
+	Key   string
+	Flags uint64
+	Value []byte
+}
+
+// PlanImport compares the entries against the target client and returns what an import would do
+func PlanImport(client Client, entries []*ExportedKV, rewrite *KeyRewrite) ([]*ImportPlanItem, error) {
+	var plan []*ImportPlanItem
+	for _, e := range entries {
+		value, err := base64.StdEncoding.DecodeString(e.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding value of key %s - %s", e.Key, err.Error())
+		}
+		item := &ImportPlanItem{
+			SourceKey: e.Key,
+			Key:       rewrite.Apply(e.Key),
+			Flags:     e.Flags,
+			Value:     value,
+		}
+		existing, err := client.Get(item.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading existing key %s - %s", item.Key, err.Error())
+		}
+		switch {
+		case existing == nil:
+			item.Action = ImportCreate
+		case existing.Flags != item.Flags || !bytes.Equal(existing.Value, item.Value):
+			item.Action = ImportUpdate
+		default:
+			item.Action = ImportUnchanged
+		}
+		plan = append(plan, item)
+	}
+	return plan, nil
+}
+
+// ApplyImport writes all the created and updated keys of the plan
+func ApplyImport(client Client, plan []*ImportPlanItem) error {
+	for _, item := range plan {
+		if item.Action == ImportUnchanged {
+			continue
+		}
+		pair := &c.KVPair{Key: item.Key, Flags: item.Flags, Value: item.Value}
+		if err := client.Put(pair); err != nil {
+			return fmt.Errorf("failed writing key %s - %s", item.Key, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package consul
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	c "github.com/hashicorp/consul/api"
+	"github.com/magiconair/properties/assert"
+)
+
+// fakeKV a Client with an in memory KV store, catalog methods are not used by imports
+type fakeKV struct {
+	Client
+	pairs   map[string]*c.KVPair
+	puts    []string
+	failPut string
+}
+
+func (f *fakeKV) Get(key string) (*c.KVPair, error) {
+	return f.pairs[key], nil
+}
+
+func (f *fakeKV) Put(pair *c.KVPair) error {
+	if pair.Key == f.failPut {
+		return fmt.Errorf("permission denied")
+	}
+	f.puts = append(f.puts, pair.Key)
+	f.pairs[pair.Key] = pair
+	return nil
+}
+
+func exported(key, value string, flags uint64) *ExportedKV {
+	return &ExportedKV{Key: key, Flags: flags, Value: base64.StdEncoding.EncodeToString([]byte(value))}
+}
+
+func TestParseKeyRewrite(t *testing.T) {
+	r, err := ParseKeyRewrite("^prod/(.*)=staging/$1")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, r.Apply("prod/db/url"), "staging/db/url")
+	assert.Equal(t, r.Apply("dev/prod/url"), "dev/prod/url")
+
+	// only the first = separates the pattern, replacements may contain =
+	r, err = ParseKeyRewrite("^a/=b/c=d/")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, r.Apply("a/x"), "b/c=d/x")
+
+	r, err = ParseKeyRewrite("")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, r.Apply("same"), "same")
+
+	_, err = ParseKeyRewrite("no-separator")
+	assert.Equal(t, err != nil, true)
+	_, err = ParseKeyRewrite("([=x")
+	assert.Equal(t, err != nil, true)
+}
+
+func TestPlanAndApplyImport(t *testing.T) {
+	client := &fakeKV{pairs: map[string]*c.KVPair{
+		"staging/same":  {Key: "staging/same", Value: []byte("v1")},
+		"staging/value": {Key: "staging/value", Value: []byte("old")},
+		"staging/flags": {Key: "staging/flags", Value: []byte("v1"), Flags: 1},
+	}}
+	entries := []*ExportedKV{
+		exported("prod/same", "v1", 0),
+		exported("prod/value", "new", 0),
+		exported("prod/flags", "v1", 2),
+		exported("prod/new", "v1", 0),
+	}
+	rewrite, _ := ParseKeyRewrite("^prod/=staging/")
+
+	plan, err := PlanImport(client, entries, rewrite)
+	assert.Equal(t, err, nil)
+	var actions []ImportAction
+	for _, item := range plan {
+		actions = append(actions, item.Action)
+	}
+	assert.Equal(t, actions, []ImportAction{ImportUnchanged, ImportUpdate, ImportUpdate, ImportCreate})
+	assert.Equal(t, plan[3].SourceKey, "prod/new")
+	assert.Equal(t, plan[3].Key, "staging/new")
+	assert.Equal(t, len(client.puts), 0, "planning must not write")
+
+	assert.Equal(t, ApplyImport(client, plan), nil)
+	assert.Equal(t, client.puts, []string{"staging/value", "staging/flags", "staging/new"})
+	assert.Equal(t, string(client.pairs["staging/value"].Value), "new")
+	assert.Equal(t, client.pairs["staging/flags"].Flags, uint64(2))
+
+	// applying again is a no-op
+	plan, _ = PlanImport(client, entries, rewrite)
+	client.puts = nil
+	assert.Equal(t, ApplyImport(client, plan), nil)
+	assert.Equal(t, len(client.puts), 0)
+
+	client.failPut = "staging/broken"
+	plan, _ = PlanImport(client, []*ExportedKV{exported("prod/broken", "x", 0)}, rewrite)
+	assert.Equal(t, ApplyImport(client, plan) != nil, true)
+
+	_, err = PlanImport(client, []*ExportedKV{{Key: "bad", Value: "not base64!"}}, nil)
+	assert.Equal(t, err != nil, true)
+}
@@ -0,0 +1,196 @@
+package consulsearch
+
+import (
+	"fmt"
+
+	consul "github.com/isan-rivkin/surf/lib/consul"
+)
+
+// Name returns the identifier of the matched entity (kv key, service or node name)
+func (m *Match) Name() string {
+	switch {
+	case m.Key != "":
+		return m.Key
+	case m.Service != "":
+		return m.Service
+	default:
+		return m.Node
+	}
+}
+
+// isNodeLevel nodes, node meta and node checks belong to the datacenter not to a namespace
+func (m *Match) isNodeLevel() bool {
+	switch m.Target {
+	case NodeTarget, NodeMetaTarget:
+		return true
+	case HealthCheckTarget:
+		return m.Service == ""
+	}
+	return false
+}
+
+type _matchDeduper struct {
+	seen    map[string]bool
+	matches []*Match
+}
+
+func newMatchDeduper() *_matchDeduper {
+	return &_matchDeduper{seen: map[string]bool{}, matches: []*Match{}}
+}
+
+// add appends the match unless an identical one was already found (i.e same meta on multiple service instances)
+func (d *_matchDeduper) add(m *Match) {
+	id := fmt.Sprintf("%s|%s|%s|%s|%s", m.Target, m.Service, m.Node, m.Field, m.Value)
+	if d.seen[id] {
+		return
+	}
+	d.seen[id] = true
+	d.matches = append(d.matches, m)
+}
+
+func (s *DefaultSearcher[CC, Matcher]) isMatch(i *Input, values ...string) (string, bool, error) {
+	for _, v := range values {
+		match, err := s.Comparator.IsMatch(i.Value, v)
+		if err != nil {
+			return "", false, err
+		}
+		if match {
+			return v, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// searchCatalog withNodes false skips nodes and node checks, they are the same in every namespace of the datacenter
+func (s *DefaultSearcher[CC, Matcher]) searchCatalog(client consul.Client, dc string, i *Input, withNodes bool) ([]*Match, error) {
+	d := newMatchDeduper()
+
+	if i.hasTarget(ServiceNameTarget) || i.hasTarget(ServiceTagsTarget) || i.hasTarget(ServiceMetaTarget) {
+		if err := s.searchServices(client, dc, i, d); err != nil {
+			return nil, err
+		}
+	}
+
+	if withNodes && (i.hasTarget(NodeTarget) || i.hasTarget(NodeMetaTarget)) {
+		if err := s.searchNodes(client, dc, i, d); err != nil {
+			return nil, err
+		}
+	}
+
+	if i.hasTarget(HealthCheckTarget) {
+		if err := s.searchHealthChecks(client, dc, i, withNodes, d); err != nil {
+			return nil, err
+		}
+	}
+
+	return d.matches, nil
+}
+
+func (s *DefaultSearcher[CC, Matcher]) searchServices(client consul.Client, dc string, i *Input, d *_matchDeduper) error {
+	services, err := client.ListServices()
+	if err != nil {
+		return fmt.Errorf("failed listing catalog services in datacenter %s - %s", dc, err.Error())
+	}
+
+	for service, tags := range services {
+		if i.hasTarget(ServiceNameTarget) {
+			if _, match, err := s.isMatch(i, service); err != nil {
+				return err
+			} else if match {
+				d.add(&Match{Datacenter: dc, Target: ServiceNameTarget, Service: service, Field: "name", Value: service})
+			}
+		}
+
+		if i.hasTarget(ServiceTagsTarget) {
+			for _, tag := range tags {
+				if _, match, err := s.isMatch(i, tag); err != nil {
+					return err
+				} else if match {
+					d.add(&Match{Datacenter: dc, Target: ServiceTagsTarget, Service: service, Field: "tag", Value: tag})
+				}
+			}
+		}
+
+		if !i.hasTarget(ServiceMetaTarget) {
+			continue
+		}
+
+		// service meta is per instance and requires fetching every service
+		instances, err := client.ListServiceInstances(service)
+		if err != nil {
+			return fmt.Errorf("failed listing instances of service %s in datacenter %s - %s", service, dc, err.Error())
+		}
+		for _, instance := range instances {
+			for k, v := range instance.ServiceMeta {
+				if matched, match, err := s.isMatch(i, k, v); err != nil {
+					return err
+				} else if match {
+					d.add(&Match{Datacenter: dc, Target: ServiceMetaTarget, Service: service, Field: "meta." + k, Value: matched})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *DefaultSearcher[CC, Matcher]) searchNodes(client consul.Client, dc string, i *Input, d *_matchDeduper) error {
+	nodes, err := client.ListNodes()
+	if err != nil {
+		return fmt.Errorf("failed listing catalog nodes in datacenter %s - %s", dc, err.Error())
+	}
+
+	for _, node := range nodes {
+		if i.hasTarget(NodeTarget) {
+			if _, match, err := s.isMatch(i, node.Node); err != nil {
+				return err
+			} else if match {
+				d.add(&Match{Datacenter: dc, Target: NodeTarget, Node: node.Node, Field: "name", Value: node.Node})
+			}
+			if _, match, err := s.isMatch(i, node.Address); err != nil {
+				return err
+			} else if match {
+				d.add(&Match{Datacenter: dc, Target: NodeTarget, Node: node.Node, Field: "address", Value: node.Address})
+			}
+		}
+
+		if i.hasTarget(NodeMetaTarget) {
+			for k, v := range node.Meta {
+				if matched, match, err := s.isMatch(i, k, v); err != nil {
+					return err
+				} else if match {
+					d.add(&Match{Datacenter: dc, Target: NodeMetaTarget, Node: node.Node, Field: "meta." + k, Value: matched})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *DefaultSearcher[CC, Matcher]) searchHealthChecks(client consul.Client, dc string, i *Input, withNodes bool, d *_matchDeduper) error {
+	checks, err := client.ListHealthChecks()
+	if err != nil {
+		return fmt.Errorf("failed listing health checks in datacenter %s - %s", dc, err.Error())
+	}
+
+	for _, check := range checks {
+		// node checks have no service
+		if !withNodes && check.ServiceName == "" {
+			continue
+		}
+		matched, match, err := s.isMatch(i, check.Name, check.Output)
+		if err != nil {
+			return err
+		}
+		if match {
+			d.add(&Match{
+				Datacenter: dc,
+				Target:     HealthCheckTarget,
+				Node:       check.Node,
+				Service:    check.ServiceName,
+				Field:      fmt.Sprintf("check.%s (%s)", check.Name, check.Status),
+				Value:      matched,
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package consulsearch_test
+
+import (
+	"testing"
+
+	api "github.com/hashicorp/consul/api"
+	common "github.com/isan-rivkin/surf/lib/search"
+	search "github.com/isan-rivkin/surf/lib/search/consulsearch"
+	"github.com/magiconair/properties/assert"
+)
+
+func newCatalogClient() *fakeClient {
+	return &fakeClient{
+		datacenter: "dc1",
+		pairs:      api.KVPairs{{Key: "billing/config"}},
+		services: map[string][]string{
+			"billing": {"v1", "primary"},
+			"web":     {"billing-frontend"},
+		},
+		instances: map[string][]*api.CatalogService{
+			// same meta on every instance must be reported once
+			"billing": {
+				{ServiceMeta: map[string]string{"team": "payments"}},
+				{ServiceMeta: map[string]string{"team": "payments"}},
+			},
+			"web": {{ServiceMeta: map[string]string{"owner": "billing-team"}}},
+		},
+		nodes: []*api.Node{
+			{Node: "billing-node-1", Address: "10.0.0.1", Meta: map[string]string{"rack": "r1"}},
+			{Node: "web-node-1", Address: "10.0.0.2", Meta: map[string]string{"role": "billing"}},
+		},
+		checks: api.HealthChecks{
+			{Node: "web-node-1", Name: "http", Output: "GET /billing 200", Status: "passing", ServiceName: "web"},
+			{Node: "web-node-1", Name: "disk", Output: "ok", Status: "passing"},
+		},
+	}
+}
+
+func TestSearchCatalogTargets(t *testing.T) {
+	s := search.NewSearcher[*fakeClient, common.Matcher](newCatalogClient(), common.NewDefaultRegexMatcher())
+
+	cases := []struct {
+		target   search.SearchTarget
+		expected []string
+	}{
+		{search.ServiceNameTarget, []string{"billing"}},
+		{search.ServiceTagsTarget, []string{"web"}},
+		{search.ServiceMetaTarget, []string{"web"}},
+		{search.NodeTarget, []string{"billing-node-1"}},
+		{search.NodeMetaTarget, []string{"web-node-1"}},
+		{search.HealthCheckTarget, []string{"web"}},
+	}
+	for _, c := range cases {
+		out, err := s.Search(search.NewSearchInput("billing", "/").WithTargets([]search.SearchTarget{c.target}))
+		assert.Equal(t, err, nil)
+		var names []string
+		for _, m := range out.Matches {
+			assert.Equal(t, m.Target, c.target, "only the requested target is searched")
+			names = append(names, m.Name())
+		}
+		assert.Equal(t, names, c.expected, string(c.target))
+	}
+}
+
+func TestSearchCatalogDedup(t *testing.T) {
+	s := search.NewSearcher[*fakeClient, common.Matcher](newCatalogClient(), common.NewDefaultRegexMatcher())
+
+	out, err := s.Search(search.NewSearchInput("payments", "/").WithTargets([]search.SearchTarget{search.ServiceMetaTarget}))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(out.Matches), 1, "identical meta on multiple instances is one match")
+	assert.Equal(t, out.Matches[0].Field, "meta.team")
+	assert.Equal(t, out.Matches[0].Value, "payments")
+
+	// kv and catalog targets together, sorted by target
+	out, err = s.Search(search.NewSearchInput("billing", "/").WithTargets([]search.SearchTarget{search.KVTarget, search.ServiceNameTarget}))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(out.Matches), 2)
+	assert.Equal(t, out.Matches[0].Target, search.KVTarget)
+	assert.Equal(t, out.Matches[1].Target, search.ServiceNameTarget)
+}
+
+func TestSearchCatalogNodesOncePerDatacenter(t *testing.T) {
+	client := newCatalogClient()
+	client.checks = append(client.checks, &api.HealthCheck{Node: "billing-node-1", Name: "billing-disk", Status: "passing"})
+	s := search.NewSearcher[*fakeClient, common.Matcher](client, common.NewDefaultRegexMatcher())
+
+	targets := []search.SearchTarget{search.ServiceNameTarget, search.NodeTarget, search.HealthCheckTarget}
+	out, err := s.Search(search.NewSearchInput("billing", "/").WithNamespaces([]string{"a", "b"}).WithTargets(targets))
+	assert.Equal(t, err, nil)
+	counts := map[search.SearchTarget]int{}
+	for _, m := range out.Matches {
+		counts[m.Target]++
+		if m.Target == search.NodeTarget || (m.Target == search.HealthCheckTarget && m.Service == "") {
+			assert.Equal(t, m.Namespace, "", "nodes are not namespaced")
+		}
+	}
+	// services and service checks per namespace, nodes and node checks once
+	assert.Equal(t, counts, map[search.SearchTarget]int{search.ServiceNameTarget: 2, search.NodeTarget: 1, search.HealthCheckTarget: 3})
+}
@@ -0,0 +1,100 @@
+package consulsearch_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	api "github.com/hashicorp/consul/api"
+	consul "github.com/isan-rivkin/surf/lib/consul"
+)
+
+// fakeClient an in memory consul, every datacenter shares the data unless it is in failing
+type fakeClient struct {
+	datacenter string
+	namespace  string
+	failing    map[string]bool
+	pairs      api.KVPairs
+	services   map[string][]string
+	instances  map[string][]*api.CatalogService
+	nodes      []*api.Node
+	checks     api.HealthChecks
+}
+
+func (f *fakeClient) err() error {
+	if f.failing[f.datacenter] {
+		return fmt.Errorf("datacenter %s unavailable", f.datacenter)
+	}
+	return nil
+}
+
+func (f *fakeClient) List(prefix string) (api.KVPairs, error) {
+	return f.pairs, f.err()
+}
+
+func (f *fakeClient) Get(key string) (*api.KVPair, error) {
+	if err := f.err(); err != nil {
+		return nil, err
+	}
+	for _, p := range f.pairs {
+		if p.Key == key {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeClient) Put(pair *api.KVPair) error {
+	if err := f.err(); err != nil {
+		return err
+	}
+	for idx, p := range f.pairs {
+		if p.Key == pair.Key {
+			f.pairs[idx] = pair
+			return nil
+		}
+	}
+	f.pairs = append(f.pairs, pair)
+	return nil
+}
+
+func (f *fakeClient) WatchList(ctx context.Context, prefix string, waitIndex uint64, waitTime time.Duration) (api.KVPairs, uint64, error) {
+	return f.pairs, waitIndex + 1, f.err()
+}
+
+func (f *fakeClient) ListServices() (map[string][]string, error) {
+	return f.services, f.err()
+}
+
+func (f *fakeClient) ListServiceInstances(service string) ([]*api.CatalogService, error) {
+	return f.instances[service], f.err()
+}
+
+func (f *fakeClient) ListNodes() ([]*api.Node, error) {
+	return f.nodes, f.err()
+}
+
+func (f *fakeClient) ListHealthChecks() (api.HealthChecks, error) {
+	return f.checks, f.err()
+}
+
+func (f *fakeClient) GetSchemeType() string                 { return "http" }
+func (f *fakeClient) GetConsulAddr() string                 { return "127.0.0.1:8500" }
+func (f *fakeClient) GetConsulUIBaseAddr() (string, error)  { return "http://127.0.0.1:8500/ui", nil }
+func (f *fakeClient) GetCurrentDatacenter() (string, error) { return f.datacenter, nil }
+func (f *fakeClient) ListDatacenters() ([]string, error)    { return []string{f.datacenter}, nil }
+func (f *fakeClient) ListNamespaces() ([]string, error)     { return []string{f.namespace}, nil }
+func (f *fakeClient) GetNamespace() string                  { return f.namespace }
+func (f *fakeClient) GetPartition() string                  { return "" }
+
+func (f *fakeClient) WithDatacenter(dc string) (consul.Client, error) {
+	c := *f
+	c.datacenter = dc
+	return &c, nil
+}
+
+func (f *fakeClient) WithNamespace(ns string) (consul.Client, error) {
+	c := *f
+	c.namespace = ns
+	return &c, nil
+}
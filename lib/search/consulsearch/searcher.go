@@ -2,9 +2,47 @@ package consulsearch
 
 import (
 	"fmt"
+	"math"
+	"sort"
 
+	api "github.com/hashicorp/consul/api"
+	workPool "github.com/isan-rivkin/surf/lib/common"
 	consul "github.com/isan-rivkin/surf/lib/consul"
 	common "github.com/isan-rivkin/surf/lib/search"
+	log "github.com/sirupsen/logrus"
+)
+
+type _scope struct {
+	Datacenter string
+	Namespace  string
+	// nodes are not namespaced, they are searched in the first namespace of every datacenter only
+	WithNodes bool
+}
+
+type _dcAsyncRes struct {
+	Datacenter string
+	Namespace  string
+	Matches    []*Match
+	Err        error
+}
+
+type SearchTarget string
+
+const (
+	// match against keys in the KV store
+	KVTarget SearchTarget = "kv"
+	// match against service names in the catalog
+	ServiceNameTarget SearchTarget = "service"
+	// match against service tags
+	ServiceTagsTarget SearchTarget = "service_tags"
+	// match against service meta keys and values of every service instance
+	ServiceMetaTarget SearchTarget = "service_meta"
+	// match against node names and addresses
+	NodeTarget SearchTarget = "node"
+	// match against node meta keys and values
+	NodeMetaTarget SearchTarget = "node_meta"
+	// match against health check names and outputs
+	HealthCheckTarget SearchTarget = "health_check"
 )
 
 type Input struct {
@@ -14,10 +52,36 @@ type Input struct {
 	Value string
 	// TODO: implement search keys content
 	SearchKeysContent bool
+	// datacenters to search in concurrently, if empty the client datacenter is used
+	Datacenters []string
+	// namespaces to search in (Consul Enterprise), if empty the client namespace is used
+	Namespaces []string
+	// max number of go routines when searching multiple datacenters and namespaces
+	Parallel int
+	// what to match against, if empty only KV is searched
+	Targets []SearchTarget
+}
+
+type Match struct {
+	Datacenter string
+	Namespace  string
+	Target     SearchTarget
+	// the KV key when Target is KVTarget
+	Key string
+	// the service the match belongs to (if any)
+	Service string
+	// the node the match belongs to (if any)
+	Node string
+	// the attribute that matched i.e tag, meta.<key>, address, check.<name>
+	Field string
+	// the attribute value that matched
+	Value string
+	// the raw pair when Target is KVTarget
+	Pair *api.KVPair
 }
 
 type Output struct {
-	Matches []string
+	Matches []*Match
 }
 
 type Searcher[C consul.Client, M common.Matcher] interface {
@@ -33,9 +97,37 @@ func NewSearchInput(value string, basePath string) *Input {
 	return &Input{
 		Value:    value,
 		BasePath: basePath,
+		Parallel: 10,
 	}
 }
 
+func (i *Input) WithDatacenters(dcs []string) *Input {
+	i.Datacenters = dcs
+	return i
+}
+
+func (i *Input) WithNamespaces(namespaces []string) *Input {
+	i.Namespaces = namespaces
+	return i
+}
+
+func (i *Input) WithTargets(targets []SearchTarget) *Input {
+	i.Targets = targets
+	return i
+}
+
+func (i *Input) hasTarget(t SearchTarget) bool {
+	if len(i.Targets) == 0 {
+		return t == KVTarget
+	}
+	for _, target := range i.Targets {
+		if target == t {
+			return true
+		}
+	}
+	return false
+}
+
 func NewSearcher[C consul.Client, Comp common.Matcher](c consul.Client, m common.Matcher) Searcher[consul.Client, common.Matcher] {
 	return &DefaultSearcher[consul.Client, common.Matcher]{
 		Client:     c,
@@ -44,13 +136,136 @@ func NewSearcher[C consul.Client, Comp common.Matcher](c consul.Client, m common
 }
 
 func (s *DefaultSearcher[CC, Matcher]) Search(i *Input) (*Output, error) {
-	pairs, err := s.Client.List(i.BasePath)
+	// resolved once, the client caches the agent datacenter and is not safe to resolve from every worker
+	baseDC, err := s.Client.GetCurrentDatacenter()
+	if err != nil {
+		return nil, fmt.Errorf("failed getting current datacenter - %s", err.Error())
+	}
+	datacenters := i.Datacenters
+	if len(datacenters) == 0 {
+		datacenters = []string{baseDC}
+	}
+	namespaces := i.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{s.Client.GetNamespace()}
+	}
+
+	var scopes []*_scope
+	for _, dc := range datacenters {
+		for idx, ns := range namespaces {
+			scopes = append(scopes, &_scope{Datacenter: dc, Namespace: ns, WithNodes: idx == 0})
+		}
+	}
+
+	workersNum := math.Max(1, math.Min(float64(len(scopes)), float64(i.Parallel)))
+	pool := workPool.NewWorkerPool(int(workersNum))
+	asyncResults := make(chan *_dcAsyncRes, len(scopes))
+
+	for _, scope := range scopes {
+		scope := scope
+		pool.Submit(func() {
+			res := &_dcAsyncRes{Datacenter: scope.Datacenter, Namespace: scope.Namespace}
+			client, err := s.scopedClient(scope, baseDC)
+			if err != nil {
+				res.Err = err
+			} else {
+				res.Matches, res.Err = s.searchClient(client, scope.Datacenter, i, scope.WithNodes)
+			}
+			asyncResults <- res
+		})
+	}
+
+	pool.RunAll()
+	close(asyncResults)
+
+	output := &Output{Matches: []*Match{}}
+	var errs []error
+	for r := range asyncResults {
+		if r.Err != nil {
+			// a single scope search keeps failing loudly like before
+			if len(scopes) == 1 {
+				return nil, r.Err
+			}
+			log.WithError(r.Err).WithFields(log.Fields{
+				"datacenter": r.Datacenter,
+				"namespace":  r.Namespace,
+			}).Error("failed searching in datacenter")
+			errs = append(errs, r.Err)
+			continue
+		}
+		output.Matches = append(output.Matches, r.Matches...)
+	}
+	// partial results are still useful, nothing searched is a failure not 0 matches
+	if len(errs) == len(scopes) {
+		return nil, fmt.Errorf("failed searching in all %d datacenters and namespaces - %s", len(scopes), errs[0].Error())
+	}
+	sortMatches(output.Matches)
+	return output, nil
+}
+
+// scopedClient baseDC is the datacenter of the base client
+func (s *DefaultSearcher[CC, Matcher]) scopedClient(scope *_scope, baseDC string) (consul.Client, error) {
+	client := s.Client
+	var err error
+	if baseDC != scope.Datacenter {
+		if client, err = client.WithDatacenter(scope.Datacenter); err != nil {
+			return nil, err
+		}
+	}
+	if client.GetNamespace() != scope.Namespace {
+		if client, err = client.WithNamespace(scope.Namespace); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// keep output stable regardless of which datacenter answered first or catalog map ordering
+func sortMatches(matches []*Match) {
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].Datacenter != matches[b].Datacenter {
+			return matches[a].Datacenter < matches[b].Datacenter
+		}
+		if matches[a].Namespace != matches[b].Namespace {
+			return matches[a].Namespace < matches[b].Namespace
+		}
+		if matches[a].Target != matches[b].Target {
+			return matches[a].Target < matches[b].Target
+		}
+		return matches[a].Name() < matches[b].Name()
+	})
+}
+
+func (s *DefaultSearcher[CC, Matcher]) searchClient(client consul.Client, dc string, i *Input, withNodes bool) ([]*Match, error) {
+	matches := []*Match{}
+	if i.hasTarget(KVTarget) {
+		kvMatches, err := s.searchKV(client, dc, i)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, kvMatches...)
+	}
+	catalogMatches, err := s.searchCatalog(client, dc, i, withNodes)
+	if err != nil {
+		return nil, err
+	}
+	matches = append(matches, catalogMatches...)
+	for _, m := range matches {
+		if !m.isNodeLevel() {
+			m.Namespace = client.GetNamespace()
+		}
+	}
+	return matches, nil
+}
+
+func (s *DefaultSearcher[CC, Matcher]) searchKV(client consul.Client, dc string, i *Input) ([]*Match, error) {
+	pairs, err := client.List(i.BasePath)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed listing all keys under the prefix %s - %s", i.BasePath, err.Error())
+		return nil, fmt.Errorf("failed listing all keys under the prefix %s in datacenter %s - %s", i.BasePath, dc, err.Error())
 	}
 
-	matches := []string{}
+	matches := []*Match{}
 	for _, pair := range pairs {
 		key := pair.Key
 		value := i.Value
@@ -61,8 +276,8 @@ func (s *DefaultSearcher[CC, Matcher]) Search(i *Input) (*Output, error) {
 		}
 
 		if match {
-			matches = append(matches, key)
+			matches = append(matches, &Match{Key: key, Datacenter: dc, Target: KVTarget, Field: "key", Value: key, Pair: pair})
 		}
 	}
-	return &Output{Matches: matches}, nil
+	return matches, nil
 }
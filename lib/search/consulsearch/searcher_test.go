@@ -0,0 +1,29 @@
+package consulsearch_test
+
+import (
+	"testing"
+
+	api "github.com/hashicorp/consul/api"
+	common "github.com/isan-rivkin/surf/lib/search"
+	search "github.com/isan-rivkin/surf/lib/search/consulsearch"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestSearchDatacentersFailures(t *testing.T) {
+	client := &fakeClient{
+		datacenter: "dc1",
+		failing:    map[string]bool{"dc2": true},
+		pairs:      api.KVPairs{{Key: "app/db-password"}, {Key: "app/port"}},
+	}
+	s := search.NewSearcher[*fakeClient, common.Matcher](client, common.NewDefaultRegexMatcher())
+
+	out, err := s.Search(search.NewSearchInput("db", "/").WithDatacenters([]string{"dc1", "dc2"}))
+	assert.Equal(t, err, nil, "partial failure must return the datacenters that answered")
+	assert.Equal(t, len(out.Matches), 1)
+	assert.Equal(t, out.Matches[0].Datacenter, "dc1")
+
+	client.failing["dc1"] = true
+	out, err = s.Search(search.NewSearchInput("db", "/").WithDatacenters([]string{"dc1", "dc2"}))
+	assert.Equal(t, err != nil, true, "all datacenters failing must not look like 0 matches")
+	assert.Equal(t, out == nil, true)
+}
@@ -0,0 +1,141 @@
+package consulsearch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	consul "github.com/isan-rivkin/surf/lib/consul"
+	common "github.com/isan-rivkin/surf/lib/search"
+	log "github.com/sirupsen/logrus"
+)
+
+type WatchEventType string
+
+const (
+	KeyAdded    WatchEventType = "added"
+	KeyModified WatchEventType = "modified"
+	KeyDeleted  WatchEventType = "deleted"
+)
+
+type WatchEvent struct {
+	Type WatchEventType
+	Key  string
+	// the consul index the change was observed at
+	Index uint64
+	Time  time.Time
+}
+
+// Snapshot maps matched keys to their ModifyIndex
+type Snapshot map[string]uint64
+
+type Watcher[C consul.Client, M common.Matcher] interface {
+	// Watch blocks until ctx is done, every change to keys matching the input is sent to events
+	Watch(ctx context.Context, i *Input, events chan<- *WatchEvent) error
+}
+
+type DefaultWatcher[C consul.Client, M common.Matcher] struct {
+	Client     consul.Client
+	Comparator common.Matcher
+	// max duration of a single blocking query
+	WaitTime time.Duration
+	// wait time before retrying a failed query
+	RetryInterval time.Duration
+}
+
+func NewWatcher[C consul.Client, Comp common.Matcher](c consul.Client, m common.Matcher) Watcher[consul.Client, common.Matcher] {
+	return &DefaultWatcher[consul.Client, common.Matcher]{
+		Client:        c,
+		Comparator:    m,
+		WaitTime:      5 * time.Minute,
+		RetryInterval: 5 * time.Second,
+	}
+}
+
+func (w *DefaultWatcher[CC, Matcher]) snapshot(ctx context.Context, i *Input, waitIndex uint64) (Snapshot, uint64, error) {
+	pairs, index, err := w.Client.WatchList(ctx, i.BasePath, waitIndex, w.WaitTime)
+	if err != nil {
+		return nil, 0, err
+	}
+	snap := Snapshot{}
+	for _, pair := range pairs {
+		match, err := w.Comparator.IsMatch(i.Value, pair.Key)
+		if err != nil {
+			return nil, 0, err
+		}
+		if match {
+			snap[pair.Key] = pair.ModifyIndex
+		}
+	}
+	return snap, index, nil
+}
+
+func (w *DefaultWatcher[CC, Matcher]) Watch(ctx context.Context, i *Input, events chan<- *WatchEvent) error {
+	current, index, err := w.snapshot(ctx, i, 0)
+	if err != nil {
+		return fmt.Errorf("failed listing initial keys under the prefix %s - %s", i.BasePath, err.Error())
+	}
+	log.WithFields(log.Fields{"index": index, "matched_keys": len(current)}).Debug("initial watch snapshot")
+
+	for {
+		next, nextIndex, err := w.snapshot(ctx, i, index)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			log.WithError(err).Warnf("blocking query failed, retrying in %s", w.RetryInterval)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(w.RetryInterval):
+			}
+			continue
+		}
+
+		// wait time passed without changes
+		if nextIndex == index {
+			continue
+		}
+
+		for _, e := range DiffSnapshots(current, next, nextIndex) {
+			// the consumer may stop reading once ctx is done
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		// index went backwards (i.e raft snapshot restore) reset according to consul docs
+		if nextIndex < index {
+			nextIndex = 0
+		}
+		current, index = next, nextIndex
+	}
+}
+
+// DiffSnapshots returns the added, modified and deleted keys between two snapshots sorted by key
+func DiffSnapshots(prev, next Snapshot, index uint64) []*WatchEvent {
+	now := time.Now()
+	events := []*WatchEvent{}
+	for k, modifyIdx := range next {
+		prevModifyIdx, exist := prev[k]
+		if !exist {
+			events = append(events, &WatchEvent{Type: KeyAdded, Key: k, Index: index, Time: now})
+		} else if prevModifyIdx != modifyIdx {
+			events = append(events, &WatchEvent{Type: KeyModified, Key: k, Index: index, Time: now})
+		}
+	}
+	for k := range prev {
+		if _, exist := next[k]; !exist {
+			events = append(events, &WatchEvent{Type: KeyDeleted, Key: k, Index: index, Time: now})
+		}
+	}
+	sort.Slice(events, func(a, b int) bool {
+		return events[a].Key < events[b].Key
+	})
+	return events
+}
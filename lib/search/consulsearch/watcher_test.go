@@ -0,0 +1,76 @@
+package consulsearch_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	api "github.com/hashicorp/consul/api"
+	common "github.com/isan-rivkin/surf/lib/search"
+	search "github.com/isan-rivkin/surf/lib/search/consulsearch"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	prev := search.Snapshot{
+		"config/a": 10,
+		"config/b": 11,
+		"config/c": 12,
+	}
+	next := search.Snapshot{
+		"config/a": 10,
+		"config/b": 15,
+		"config/d": 15,
+	}
+
+	events := search.DiffSnapshots(prev, next, 15)
+
+	expected := []struct {
+		Key  string
+		Type search.WatchEventType
+	}{
+		{"config/b", search.KeyModified},
+		{"config/c", search.KeyDeleted},
+		{"config/d", search.KeyAdded},
+	}
+
+	assert.Equal(t, len(events), len(expected), "wrong number of events")
+	for idx, e := range expected {
+		assert.Equal(t, events[idx].Key, e.Key, "wrong key")
+		assert.Equal(t, events[idx].Type, e.Type, "wrong event type")
+		assert.Equal(t, events[idx].Index, uint64(15), "wrong index")
+	}
+
+	assert.Equal(t, len(search.DiffSnapshots(next, next, 16)), 0, "identical snapshots must not produce events")
+}
+
+// changingClient every blocking query returns a new key
+type changingClient struct {
+	*fakeClient
+}
+
+func (c *changingClient) WatchList(ctx context.Context, prefix string, waitIndex uint64, waitTime time.Duration) (api.KVPairs, uint64, error) {
+	next := waitIndex + 1
+	return api.KVPairs{{Key: fmt.Sprintf("config/%d", next), ModifyIndex: next}}, next, nil
+}
+
+func TestWatchStopsWithoutConsumer(t *testing.T) {
+	w := search.NewWatcher[*changingClient, common.Matcher](&changingClient{&fakeClient{}}, common.NewDefaultRegexMatcher())
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan *search.WatchEvent)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Watch(ctx, search.NewSearchInput("config", "/"), events)
+	}()
+
+	// one event is read then the consumer stops reading
+	<-events
+	cancel()
+	select {
+	case err := <-done:
+		assert.Equal(t, err, nil)
+	case <-time.After(time.Second):
+		t.Fatal("watch blocked on sending an event after ctx was canceled")
+	}
+}
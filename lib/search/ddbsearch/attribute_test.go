@@ -0,0 +1,54 @@
+package ddbsearch
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsu "github.com/isan-rivkin/surf/lib/awsu"
+	common "github.com/isan-rivkin/surf/lib/search"
+	"github.com/magiconair/properties/assert"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestSearchSingleObjectAttributeScope(t *testing.T) {
+	s := &DefaultSearcher[awsu.DDBApi, common.Matcher]{Comparator: common.NewDefaultRegexMatcher(), Parser: NewParserFactory()}
+	obj := map[string]*string{
+		"status": aws.String("SHIPPED"),
+		"note":   aws.String("not shipped yet"),
+	}
+	lg := log.WithField("test", t.Name())
+
+	i := &Input{Value: "not", Match: ObjectMatch}
+	matches, err := s.SearchSingleObject(i, obj, lg)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 2)
+	assert.Equal(t, matches[0].Path, "note")
+	assert.Equal(t, matches[0].IsKeyMatch, true)
+	assert.Equal(t, matches[1].IsKeyMatch, false)
+	assert.Equal(t, matches[1].Offsets, [][]int{{0, 3}})
+
+	i = i.WithAttribute("status", awsu.DDBFilterContains, "SHIP")
+	assert.Equal(t, i.Filter.Attribute, "status")
+	matches, err = s.SearchSingleObject(i, obj, lg)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 0)
+
+	i.Value = "shipped"
+	matches, _ = s.SearchSingleObject(i, obj, lg)
+	assert.Equal(t, len(matches), 1)
+	assert.Equal(t, matches[0].Path, "status")
+	assert.Equal(t, matches[0].Offsets, [][]int{{0, 7}})
+
+	// attribute name and value both match
+	i = &Input{Value: "stat", Match: ObjectMatch}
+	obj["status"] = aws.String("stat-ok")
+	matches, _ = s.SearchSingleObject(i, obj, lg)
+	assert.Equal(t, len(matches), 2)
+	assert.Equal(t, matches[0].IsKeyMatch, true)
+	assert.Equal(t, matches[1].IsKeyMatch, false)
+	assert.Equal(t, matches[1].Offsets, [][]int{{0, 4}})
+
+	// scope only, no push down
+	i = (&Input{Value: "x", Match: ObjectMatch}).WithAttribute("note", "", "")
+	assert.Equal(t, i.Filter == nil, true)
+}
@@ -0,0 +1,310 @@
+package ddbsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	awsu "github.com/isan-rivkin/surf/lib/awsu"
+	log "github.com/sirupsen/logrus"
+)
+
+// time between checkpoint writes while scanning
+const checkpointSaveInterval = 5 * time.Second
+
+// TableCheckpoint scan progress of a single table
+type TableCheckpoint struct {
+	// the key to continue each segment from
+	StartKeys map[int]map[string]*dynamodb.AttributeValue `json:"start_keys,omitempty"`
+	// segments that were scanned to the end
+	CompletedSegments map[int]bool `json:"completed_segments,omitempty"`
+}
+
+// Checkpoint persists scan progress (LastEvaluatedKey per table and segment) and the hits found so far,
+// a search with the checkpoint continues where the previous one stopped
+type Checkpoint struct {
+	Query            string                      `json:"query"`
+	TablePattern     string                      `json:"table_pattern"`
+	Segments         int                         `json:"segments"`
+	Attribute        string                      `json:"attribute,omitempty"`
+	Filter           *awsu.DDBFilter             `json:"filter,omitempty"`
+	Match            MatchLevel                  `json:"match"`
+	DecodeDepth      int                         `json:"decode_depth"`
+	ProtoDescriptors string                      `json:"proto_descriptors,omitempty"`
+	ProtoTypes       map[string]string           `json:"proto_types,omitempty"`
+	WithGlobalTables bool                        `json:"with_global_tables"`
+	Tables           map[string]*TableCheckpoint `json:"tables"`
+	// hits are encoded once when found so saving the checkpoint does not marshal them again
+	Hits []json.RawMessage `json:"hits"`
+
+	path     string
+	mu       sync.Mutex
+	dirty    bool
+	hits     []*OutputHit
+	seenHits map[string]bool
+}
+
+func NewCheckpoint(path string, i *Input) *Checkpoint {
+	return &Checkpoint{
+		Query:            i.Value,
+		TablePattern:     i.TableNamePattern,
+		Segments:         segmentsCount(i.Segments),
+		Attribute:        i.Attribute,
+		Filter:           i.Filter,
+		Match:            i.Match,
+		DecodeDepth:      i.DecodeDepth,
+		ProtoDescriptors: protoFingerprint(i),
+		ProtoTypes:       i.ProtoTypes,
+		WithGlobalTables: i.WithGlobalTables,
+		Tables:           map[string]*TableCheckpoint{},
+		path:             path,
+		seenHits:         map[string]bool{},
+	}
+}
+
+// protoFingerprint empty when binary attributes are not decoded with proto descriptors
+func protoFingerprint(i *Input) string {
+	if i.ProtoDescriptors == nil {
+		return ""
+	}
+	return i.ProtoDescriptors.Fingerprint()
+}
+
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading checkpoint %s %s", path, err.Error())
+	}
+	c := &Checkpoint{}
+	if err := json.Unmarshal(raw, c); err != nil {
+		return nil, fmt.Errorf("failed parsing checkpoint %s %s", path, err.Error())
+	}
+	if c.Tables == nil {
+		c.Tables = map[string]*TableCheckpoint{}
+	}
+	c.path = path
+	c.seenHits = map[string]bool{}
+	for _, raw := range c.Hits {
+		h := &OutputHit{}
+		if err := json.Unmarshal(raw, h); err != nil {
+			return nil, fmt.Errorf("failed parsing checkpoint hit %s %s", path, err.Error())
+		}
+		c.hits = append(c.hits, h)
+		c.seenHits[hitID(h)] = true
+	}
+	return c, nil
+}
+
+func segmentsCount(segments int) int {
+	if segments < 1 {
+		return 1
+	}
+	return segments
+}
+
+func (c *Checkpoint) Path() string {
+	return c.path
+}
+
+// Validate a checkpoint can only continue the same search, settings that change which items match must be equal
+func (c *Checkpoint) Validate(i *Input) error {
+	if c.Query != i.Value || c.TablePattern != i.TableNamePattern {
+		return fmt.Errorf("checkpoint is for query %q on tables %q not %q on %q", c.Query, c.TablePattern, i.Value, i.TableNamePattern)
+	}
+	if c.Attribute != i.Attribute {
+		return fmt.Errorf("checkpoint is for attribute %q not %q", c.Attribute, i.Attribute)
+	}
+	if filterString(c.Filter) != filterString(i.Filter) {
+		return fmt.Errorf("checkpoint is for filter %q not %q", filterString(c.Filter), filterString(i.Filter))
+	}
+	if c.Match != i.Match {
+		return fmt.Errorf("checkpoint is for match level %q not %q", c.Match, i.Match)
+	}
+	if c.DecodeDepth != i.DecodeDepth {
+		return fmt.Errorf("checkpoint is for decode depth %d not %d", c.DecodeDepth, i.DecodeDepth)
+	}
+	if c.WithGlobalTables != i.WithGlobalTables {
+		return fmt.Errorf("checkpoint is for global tables %t not %t", c.WithGlobalTables, i.WithGlobalTables)
+	}
+	if c.ProtoDescriptors != protoFingerprint(i) {
+		return fmt.Errorf("checkpoint was created with different proto descriptors")
+	}
+	if !maps.Equal(c.ProtoTypes, i.ProtoTypes) {
+		return fmt.Errorf("checkpoint is for proto types %v not %v", c.ProtoTypes, i.ProtoTypes)
+	}
+	return nil
+}
+
+func filterString(f *awsu.DDBFilter) string {
+	if f == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s(%s, %s)", f.Operator, f.Attribute, f.Value)
+}
+
+func (c *Checkpoint) table(name string) *TableCheckpoint {
+	t, exist := c.Tables[name]
+	if !exist {
+		t = &TableCheckpoint{StartKeys: map[int]map[string]*dynamodb.AttributeValue{}, CompletedSegments: map[int]bool{}}
+		c.Tables[name] = t
+	}
+	if t.StartKeys == nil {
+		t.StartKeys = map[int]map[string]*dynamodb.AttributeValue{}
+	}
+	if t.CompletedSegments == nil {
+		t.CompletedSegments = map[int]bool{}
+	}
+	return t
+}
+
+// IsTableDone true if every segment of the table was scanned to the end
+func (c *Checkpoint) IsTableDone(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, exist := c.Tables[name]
+	return exist && len(t.CompletedSegments) >= c.Segments
+}
+
+// ApplyScanOptions continues the table scan from the saved keys and records progress of every page
+func (c *Checkpoint) ApplyScanOptions(name string, opts *awsu.DDBScanOptions) {
+	c.mu.Lock()
+	t := c.table(name)
+	opts.StartKeys = map[int]map[string]*dynamodb.AttributeValue{}
+	opts.CompletedSegments = map[int]bool{}
+	for segment, key := range t.StartKeys {
+		opts.StartKeys[segment] = key
+	}
+	for segment, done := range t.CompletedSegments {
+		opts.CompletedSegments[segment] = done
+	}
+	c.mu.Unlock()
+	opts.Segments = c.Segments
+	opts.OnSegmentProgress = func(segment int, lastKey map[string]*dynamodb.AttributeValue) {
+		c.SegmentProgress(name, segment, lastKey)
+	}
+}
+
+// SegmentProgress records the key to continue from, it is written by the next save
+func (c *Checkpoint) SegmentProgress(name string, segment int, lastKey map[string]*dynamodb.AttributeValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.table(name)
+	if lastKey == nil {
+		delete(t.StartKeys, segment)
+		t.CompletedSegments[segment] = true
+	} else {
+		t.StartKeys[segment] = lastKey
+	}
+	c.dirty = true
+}
+
+// AddHit hits already in the checkpoint (i.e a page scanned again after resume) are ignored
+func (c *Checkpoint) AddHit(hit *OutputHit) bool {
+	id := hitID(hit)
+	raw, err := json.Marshal(hit)
+	if err != nil {
+		log.WithError(err).Warn("failed adding hit to scan checkpoint")
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seenHits[id] {
+		return false
+	}
+	c.seenHits[id] = true
+	c.hits = append(c.hits, hit)
+	c.Hits = append(c.Hits, raw)
+	c.dirty = true
+	return true
+}
+
+func (c *Checkpoint) AllHits() []*OutputHit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*OutputHit{}, c.hits...)
+}
+
+// SaveEvery saves changed progress in the background until the returned stop func is called
+func (c *Checkpoint) SaveEvery(interval time.Duration) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				c.mu.Lock()
+				dirty := c.dirty
+				c.mu.Unlock()
+				if !dirty {
+					continue
+				}
+				if err := c.Save(); err != nil {
+					log.WithError(err).Warn("failed saving scan checkpoint")
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// Save writes to a temp file and renames it so a crash never leaves a partial checkpoint
+func (c *Checkpoint) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed marshal checkpoint %s", err.Error())
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed writing checkpoint %s %s", c.path, err.Error())
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed writing checkpoint %s %s", c.path, err.Error())
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed writing checkpoint %s %s", c.path, err.Error())
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed writing checkpoint %s %s", c.path, err.Error())
+	}
+	c.dirty = false
+	return nil
+}
+
+// hitID items have no common id across tables, the table and the parsed item identify a hit
+func hitID(hit *OutputHit) string {
+	data, _ := json.Marshal(hit.ObjectData)
+	return hit.TableName + "|" + string(data)
+}
+
+// WithCheckpoint saved keys belong to the segments of the original scan, unset (0) segments are taken from the checkpoint
+func (i *Input) WithCheckpoint(c *Checkpoint) (*Input, error) {
+	if err := c.Validate(i); err != nil {
+		return nil, err
+	}
+	if i.Segments != 0 && segmentsCount(i.Segments) != c.Segments {
+		return nil, fmt.Errorf("checkpoint is for %d segments not %d, resume without --segments", c.Segments, i.Segments)
+	}
+	i.Checkpoint = c
+	i.Segments = c.Segments
+	return i, nil
+}
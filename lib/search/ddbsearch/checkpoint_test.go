@@ -0,0 +1,159 @@
+package ddbsearch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	awsu "github.com/isan-rivkin/surf/lib/awsu"
+	protoutil "github.com/isan-rivkin/surf/lib/common/proto"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.json")
+	i := &Input{Value: "val", TableNamePattern: "orders", Segments: 2}
+	c := NewCheckpoint(path, i)
+
+	opts := &awsu.DDBScanOptions{}
+	c.ApplyScanOptions("orders", opts)
+	assert.Equal(t, opts.Segments, 2)
+
+	key := map[string]*dynamodb.AttributeValue{"pk": {S: aws.String("order-10")}}
+	opts.OnSegmentProgress(0, key)
+	opts.OnSegmentProgress(1, nil)
+	assert.Equal(t, c.IsTableDone("orders"), false)
+
+	hit := &OutputHit{TableName: "orders", HitLevel: ObjectMatch, ObjectData: map[string]*string{"pk": aws.String("order-3")}, MatchedPath: "pk"}
+	assert.Equal(t, c.AddHit(hit), true)
+	assert.Equal(t, c.Save(), nil)
+
+	loaded, err := LoadCheckpoint(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(loaded.AllHits()), 1)
+	// the same item found again after resume is not a new hit
+	assert.Equal(t, loaded.AddHit(&OutputHit{TableName: "orders", ObjectData: map[string]*string{"pk": aws.String("order-3")}}), false)
+
+	resumed := &awsu.DDBScanOptions{}
+	loaded.ApplyScanOptions("orders", resumed)
+	assert.Equal(t, aws.StringValue(resumed.StartKeys[0]["pk"].S), "order-10")
+	assert.Equal(t, resumed.CompletedSegments, map[int]bool{1: true})
+
+	resumed.OnSegmentProgress(0, nil)
+	assert.Equal(t, loaded.IsTableDone("orders"), true)
+
+	_, err = (&Input{Value: "other", TableNamePattern: "orders"}).WithCheckpoint(loaded)
+	assert.Equal(t, err != nil, true)
+	// explicit segments must match the saved keys, unset segments are taken from the checkpoint
+	_, err = (&Input{Value: "val", TableNamePattern: "orders", Segments: 8}).WithCheckpoint(loaded)
+	assert.Equal(t, err != nil, true)
+	resumedInput, err := (&Input{Value: "val", TableNamePattern: "orders"}).WithCheckpoint(loaded)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resumedInput.Segments, 2)
+	resumedInput, err = (&Input{Value: "val", TableNamePattern: "orders", Segments: 2}).WithCheckpoint(loaded)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resumedInput.Segments, 2)
+}
+
+func TestCheckpointValidate(t *testing.T) {
+	i := &Input{Value: "val", TableNamePattern: "orders", Match: ObjectMatch, DecodeDepth: DefaultDecodeDepth}
+	i = i.WithAttribute("customer", awsu.DDBFilterContains, "val")
+	c := NewCheckpoint(filepath.Join(t.TempDir(), "scan.json"), i)
+	assert.Equal(t, c.Save(), nil)
+	loaded, err := LoadCheckpoint(c.Path())
+	assert.Equal(t, err, nil)
+
+	same := *i
+	assert.Equal(t, loaded.Validate(&same), nil)
+
+	otherAttr := same
+	otherAttr.Attribute = "order_id"
+	assert.Equal(t, loaded.Validate(&otherAttr) != nil, true)
+
+	otherFilter := same
+	otherFilter.Filter = &awsu.DDBFilter{Attribute: "customer", Operator: awsu.DDBFilterBeginsWith, Value: "val"}
+	assert.Equal(t, loaded.Validate(&otherFilter) != nil, true)
+
+	noFilter := same
+	noFilter.Filter = nil
+	assert.Equal(t, loaded.Validate(&noFilter) != nil, true)
+
+	otherMatch := same
+	otherMatch.Match = TableMatch
+	assert.Equal(t, loaded.Validate(&otherMatch) != nil, true)
+
+	otherDepth := same
+	otherDepth.DecodeDepth = 0
+	assert.Equal(t, loaded.Validate(&otherDepth) != nil, true)
+
+	globalTables := same
+	globalTables.WithGlobalTables = true
+	assert.Equal(t, loaded.Validate(&globalTables) != nil, true)
+}
+
+func TestCheckpointValidateProto(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, os.WriteFile(filepath.Join(dir, "order.proto"), []byte(testOrderProto), 0644), nil)
+	descriptors, err := protoutil.NewDescriptors([]string{"order.proto"}, []string{dir})
+	assert.Equal(t, err, nil)
+
+	i, err := (&Input{Value: "val", TableNamePattern: "orders"}).WithProtoDescriptors(descriptors, map[string]string{"payload": "shop.v1.Order"})
+	assert.Equal(t, err, nil)
+	c := NewCheckpoint(filepath.Join(t.TempDir(), "scan.json"), i)
+	assert.Equal(t, c.Save(), nil)
+	loaded, err := LoadCheckpoint(c.Path())
+	assert.Equal(t, err, nil)
+
+	// descriptors loaded again from the same files
+	reloaded, err := protoutil.NewDescriptors([]string{"order.proto"}, []string{dir})
+	assert.Equal(t, err, nil)
+	same, err := (&Input{Value: "val", TableNamePattern: "orders"}).WithProtoDescriptors(reloaded, map[string]string{"payload": "shop.v1.Order"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, loaded.Validate(same), nil)
+
+	otherTypes, err := (&Input{Value: "val", TableNamePattern: "orders"}).WithProtoDescriptors(reloaded, map[string]string{"orders:payload": "shop.v1.Order"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, loaded.Validate(otherTypes) != nil, true)
+
+	noDescriptors := &Input{Value: "val", TableNamePattern: "orders"}
+	assert.Equal(t, loaded.Validate(noDescriptors) != nil, true)
+
+	changed := strings.Replace(testOrderProto, "}", "  string note = 99;\n}", 1)
+	assert.Equal(t, os.WriteFile(filepath.Join(dir, "order.proto"), []byte(changed), 0644), nil)
+	changedDescriptors, err := protoutil.NewDescriptors([]string{"order.proto"}, []string{dir})
+	assert.Equal(t, err, nil)
+	otherDescriptors, err := (&Input{Value: "val", TableNamePattern: "orders"}).WithProtoDescriptors(changedDescriptors, map[string]string{"payload": "shop.v1.Order"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, loaded.Validate(otherDescriptors) != nil, true)
+}
+
+func TestCheckpointSaveEvery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.json")
+	c := NewCheckpoint(path, &Input{Value: "val", TableNamePattern: "orders"})
+	stop := c.SaveEvery(10 * time.Millisecond)
+	defer stop()
+
+	// nothing changed, nothing is written
+	time.Sleep(30 * time.Millisecond)
+	_, err := os.Stat(path)
+	assert.Equal(t, os.IsNotExist(err), true)
+
+	c.AddHit(&OutputHit{TableName: "orders", ObjectData: map[string]*string{"pk": aws.String("order-3")}})
+	c.SegmentProgress("orders", 0, nil)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, err = os.Stat(path); err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+	loaded, err := LoadCheckpoint(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(loaded.AllHits()), 1)
+	assert.Equal(t, loaded.IsTableDone("orders"), true)
+}
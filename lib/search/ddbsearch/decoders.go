@@ -0,0 +1,266 @@
+package ddbsearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/golang/snappy"
+	protoutil "github.com/isan-rivkin/surf/lib/common/proto"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	// decoded attributes keep the stored value under the attribute path with this suffix i.e token#raw
+	RawPathSuffix = "#raw"
+	// default number of nested encodings unwrapped i.e base64 > gzip > json
+	DefaultDecodeDepth = 3
+	// decoded payloads larger than this are dropped
+	maxDecodedSize = 10 * 1024 * 1024
+	// shorter strings are too likely to be plain words that happen to be valid base64
+	minBase64Length = 16
+)
+
+var (
+	gzipMagic         = []byte{0x1f, 0x8b}
+	zstdMagic         = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	snappyFramedMagic = []byte("\xff\x06\x00\x00sNaPpY")
+	base64Pattern     = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+)
+
+// PayloadDecoder sniffs a payload format and decodes it, ok is false if the payload is not in the format
+type PayloadDecoder interface {
+	Name() string
+	Decode(b []byte) ([]byte, bool)
+}
+
+type payloadDecoderFunc struct {
+	name   string
+	decode func(b []byte) ([]byte, bool)
+}
+
+func (d *payloadDecoderFunc) Name() string {
+	return d.name
+}
+
+func (d *payloadDecoderFunc) Decode(b []byte) ([]byte, bool) {
+	return d.decode(b)
+}
+
+// DefaultPayloadDecoders formats with a magic header first, raw snappy has no header and is tried last
+func DefaultPayloadDecoders() []PayloadDecoder {
+	return []PayloadDecoder{
+		&payloadDecoderFunc{name: "gzip", decode: decodeGzip},
+		&payloadDecoderFunc{name: "zstd", decode: decodeZstd},
+		&payloadDecoderFunc{name: "snappy", decode: decodeSnappy},
+		&payloadDecoderFunc{name: "msgpack", decode: decodeMsgpack},
+		&payloadDecoderFunc{name: "cbor", decode: decodeCBOR},
+		&payloadDecoderFunc{name: "base64", decode: decodeBase64},
+	}
+}
+
+// DecodePayload applies decoders until none fits or maxDepth is reached,
+// the result is accepted only if it ends up as readable text and the chain is the decoders used i.e base64>gzip
+func DecodePayload(b []byte, decoders []PayloadDecoder, maxDepth int) (string, string, bool) {
+	var chain []string
+	for depth := 0; depth < maxDepth; depth++ {
+		decoded := false
+		for _, d := range decoders {
+			if out, ok := d.Decode(b); ok && len(out) <= maxDecodedSize {
+				b = out
+				chain = append(chain, d.Name())
+				decoded = true
+				break
+			}
+		}
+		if !decoded {
+			break
+		}
+	}
+	if len(chain) == 0 || !protoutil.IsPrintableText(b) {
+		return "", "", false
+	}
+	return string(b), strings.Join(chain, ">"), true
+}
+
+// WithFmtDecoders decodes compressed / serialized binary attributes and base64 strings, decodedBy is filled with the decoder chain per attribute
+func WithFmtDecoders(decoders []PayloadDecoder, maxDepth int, overrideIfExist bool, delimeter string) ParserOpt {
+	return func(tableObj map[string]*dynamodb.AttributeValue, parsed *ParsedObject) {
+		for k, v := range tableObj {
+			if _, exist := parsed.Values[k]; exist && !overrideIfExist {
+				continue
+			}
+			payloads := binaryValues(v)
+			if v.S != nil {
+				payloads = [][]byte{[]byte(aws.StringValue(v.S))}
+			}
+			if len(payloads) == 0 {
+				continue
+			}
+			var values, chains []string
+			for _, payload := range payloads {
+				val, chain, ok := DecodePayload(payload, decoders, maxDepth)
+				if !ok {
+					break
+				}
+				values = append(values, val)
+				chains = append(chains, chain)
+			}
+			// all values must be decoded
+			if len(values) > 0 && len(values) == len(payloads) {
+				strVal := strings.Join(values, delimeter)
+				parsed.Values[k] = &strVal
+				parsed.DecodedBy[k] = chains[0]
+				// the stored value (i.e the base64 token itself) stays searchable
+				for path, raw := range FlattenAttribute(k, v) {
+					raw := raw
+					parsed.Values[path+RawPathSuffix] = &raw
+				}
+			}
+		}
+	}
+}
+
+func readAllLimited(r io.Reader) ([]byte, bool) {
+	out, err := io.ReadAll(io.LimitReader(r, maxDecodedSize+1))
+	if err != nil || len(out) == 0 {
+		return nil, false
+	}
+	return out, true
+}
+
+func decodeGzip(b []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(b, gzipMagic) {
+		return nil, false
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	return readAllLimited(r)
+}
+
+func decodeZstd(b []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(b, zstdMagic) {
+		return nil, false
+	}
+	r, err := zstd.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	return readAllLimited(r)
+}
+
+func decodeSnappy(b []byte) ([]byte, bool) {
+	if bytes.HasPrefix(b, snappyFramedMagic) {
+		return readAllLimited(snappy.NewReader(bytes.NewReader(b)))
+	}
+	// raw block format has no header, only accept if the content is text
+	if protoutil.IsPrintableText(b) {
+		return nil, false
+	}
+	if n, err := snappy.DecodedLen(b); err != nil || n == 0 || n > maxDecodedSize {
+		return nil, false
+	}
+	out, err := snappy.Decode(nil, b)
+	if err != nil || !protoutil.IsPrintableText(out) {
+		return nil, false
+	}
+	return out, true
+}
+
+// isMsgpackContainer fixmap, fixarray, map16/32, array16/32
+func isMsgpackContainer(c byte) bool {
+	return (c >= 0x80 && c <= 0x9f) || c == 0xdc || c == 0xdd || c == 0xde || c == 0xdf
+}
+
+func decodeMsgpack(b []byte) ([]byte, bool) {
+	if len(b) == 0 || !isMsgpackContainer(b[0]) {
+		return nil, false
+	}
+	r := bytes.NewReader(b)
+	v, err := msgpack.NewDecoder(r).DecodeInterface()
+	// the whole payload must be a single value
+	if err != nil || r.Len() > 0 {
+		return nil, false
+	}
+	return marshalDecodedValue(v)
+}
+
+// isCBORContainer major type 4 (array) or 5 (map), or the self describe tag
+func isCBORContainer(b []byte) bool {
+	major := b[0] >> 5
+	return major == 4 || major == 5 || bytes.HasPrefix(b, []byte{0xd9, 0xd9, 0xf7})
+}
+
+func decodeCBOR(b []byte) ([]byte, bool) {
+	if len(b) == 0 || !isCBORContainer(b) {
+		return nil, false
+	}
+	var v interface{}
+	// the whole payload must be a single value
+	if rest, err := cbor.UnmarshalFirst(b, &v); err != nil || len(rest) > 0 {
+		return nil, false
+	}
+	return marshalDecodedValue(v)
+}
+
+func decodeBase64(b []byte) ([]byte, bool) {
+	s := strings.TrimSpace(string(b))
+	if len(s) < minBase64Length || len(s)%4 != 0 || !base64Pattern.MatchString(s) {
+		return nil, false
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding} {
+		if out, err := enc.DecodeString(s); err == nil && len(out) > 0 {
+			return out, true
+		}
+	}
+	return nil, false
+}
+
+// marshalDecodedValue msgpack and cbor decode maps with non string keys, json requires string keys
+func marshalDecodedValue(v interface{}) ([]byte, bool) {
+	out, err := json.Marshal(jsonCompatible(v))
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func jsonCompatible(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = jsonCompatible(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = jsonCompatible(item)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(val))
+		for idx, item := range val {
+			l[idx] = jsonCompatible(item)
+		}
+		return l
+	case []byte:
+		return binaryToString(val)
+	default:
+		return val
+	}
+}
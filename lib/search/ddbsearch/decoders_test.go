@@ -0,0 +1,91 @@
+package ddbsearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/golang/snappy"
+	"github.com/magiconair/properties/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(b)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, w.Close(), nil)
+	return buf.Bytes()
+}
+
+func TestDecodePayload(t *testing.T) {
+	decoders := DefaultPayloadDecoders()
+	doc := []byte(`{"user":"cust-1"}`)
+
+	val, chain, ok := DecodePayload(gzipBytes(t, doc), decoders, DefaultDecodeDepth)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, val, string(doc))
+	assert.Equal(t, chain, "gzip")
+
+	b64 := base64.StdEncoding.EncodeToString(gzipBytes(t, doc))
+	val, chain, _ = DecodePayload([]byte(b64), decoders, DefaultDecodeDepth)
+	assert.Equal(t, val, string(doc))
+	assert.Equal(t, chain, "base64>gzip")
+
+	// depth limit stops before the payload is readable
+	_, _, ok = DecodePayload([]byte(b64), decoders, 1)
+	assert.Equal(t, ok, false)
+
+	val, chain, _ = DecodePayload(snappy.Encode(nil, bytes.Repeat(doc, 3)), decoders, DefaultDecodeDepth)
+	assert.Equal(t, val, string(bytes.Repeat(doc, 3)))
+	assert.Equal(t, chain, "snappy")
+
+	packed, err := msgpack.Marshal(map[string]interface{}{"user": "cust-1"})
+	assert.Equal(t, err, nil)
+	val, chain, _ = DecodePayload(packed, decoders, DefaultDecodeDepth)
+	assert.Equal(t, val, string(doc))
+	assert.Equal(t, chain, "msgpack")
+
+	encoded, err := cbor.Marshal(map[string]interface{}{"user": "cust-1"})
+	assert.Equal(t, err, nil)
+	val, chain, _ = DecodePayload(encoded, decoders, DefaultDecodeDepth)
+	assert.Equal(t, val, string(doc))
+	assert.Equal(t, chain, "cbor")
+
+	// plain text is left as is
+	_, _, ok = DecodePayload([]byte("just a regular sentence"), decoders, DefaultDecodeDepth)
+	assert.Equal(t, ok, false)
+}
+
+func TestWithFmtDecoders(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"body":  {S: aws.String(base64.StdEncoding.EncodeToString(gzipBytes(t, []byte("hello world"))))},
+		"plain": {S: aws.String("hello")},
+	}
+	p := NewObjDefaultParser(WithFmtDecoders(DefaultPayloadDecoders(), DefaultDecodeDepth, false, " "), WithFmtTyped(nil, false, false))
+	parsed, err := p.Parse(item)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, aws.StringValue(parsed.Values["body"]), "hello world")
+	assert.Equal(t, aws.StringValue(parsed.Values["plain"]), "hello")
+	assert.Equal(t, parsed.DecodedByPath("body"), "base64>gzip")
+	assert.Equal(t, parsed.DecodedByPath("plain"), "")
+}
+
+func TestWithFmtDecodersKeepsRawValue(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(gzipBytes(t, []byte("hello world")))
+	item := map[string]*dynamodb.AttributeValue{
+		"body": {S: aws.String(encoded)},
+	}
+	p := NewObjDefaultParser(WithFmtDecoders(DefaultPayloadDecoders(), DefaultDecodeDepth, false, " "), WithFmtTyped(nil, false, false))
+	parsed, err := p.Parse(item)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, aws.StringValue(parsed.Values["body"]), "hello world")
+	assert.Equal(t, aws.StringValue(parsed.Values["body"+RawPathSuffix]), encoded)
+	assert.Equal(t, IsAttributePath("body"+RawPathSuffix, "body"), true)
+	assert.Equal(t, parsed.DecodedByPath("body"+RawPathSuffix), "")
+}
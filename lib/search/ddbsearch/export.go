@@ -0,0 +1,351 @@
+package ddbsearch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	awsu "github.com/isan-rivkin/surf/lib/awsu"
+	workPool "github.com/isan-rivkin/surf/lib/common"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	s3URLPrefix       = "s3://"
+	exportSummaryFile = "manifest-summary.json"
+	// items are up to 400KB, typed json of an item can be larger
+	maxExportLineLength = 4 * 1024 * 1024
+
+	ExportFormatDynamoDBJSON = "DYNAMODB_JSON"
+)
+
+// ExportSummary manifest-summary.json written by ExportTableToPointInTime
+type ExportSummary struct {
+	ExportArn          string `json:"exportArn"`
+	TableArn           string `json:"tableArn"`
+	ExportFormat       string `json:"exportFormat"`
+	ExportType         string `json:"exportType"`
+	ItemCount          int64  `json:"itemCount"`
+	ManifestFilesS3Key string `json:"manifestFilesS3Key"`
+}
+
+// TableName from arn:aws:dynamodb:region:account:table/name
+func (e *ExportSummary) TableName() string {
+	_, name, found := strings.Cut(e.TableArn, ":table/")
+	if !found {
+		return e.TableArn
+	}
+	return name
+}
+
+// ExportDataFile a line of manifest-files.json
+type ExportDataFile struct {
+	ItemCount     int64  `json:"itemCount"`
+	DataFileS3Key string `json:"dataFileS3Key"`
+}
+
+// exportLine full exports have Item, incremental exports have the NewImage (nil for deleted items)
+type exportLine struct {
+	Item     map[string]*dynamodb.AttributeValue `json:"Item"`
+	NewImage map[string]*dynamodb.AttributeValue `json:"NewImage"`
+}
+
+// ExportSource where the export files are, an s3 prefix or a local copy
+type ExportSource interface {
+	// ListSummaries the manifest-summary.json of every export under the location
+	ListSummaries() ([]string, error)
+	// Open opens the summary itself or a key listed in its manifests
+	Open(summary, key string) (io.ReadCloser, error)
+}
+
+// NewExportSource s3://bucket/prefix or a local directory, the location may be a single export or contain several
+func NewExportSource(location string, c awsu.S3API) (ExportSource, error) {
+	if strings.HasPrefix(location, s3URLPrefix) {
+		if c == nil {
+			return nil, fmt.Errorf("s3 client required for export %s", location)
+		}
+		bucket, prefix, _ := strings.Cut(strings.TrimPrefix(location, s3URLPrefix), "/")
+		return &s3ExportSource{client: c, bucket: bucket, prefix: prefix}, nil
+	}
+	if _, err := os.Stat(location); err != nil {
+		return nil, fmt.Errorf("failed opening export %s", err.Error())
+	}
+	return &localExportSource{location: location}, nil
+}
+
+type s3ExportSource struct {
+	client awsu.S3API
+	bucket string
+	prefix string
+}
+
+func (s *s3ExportSource) ListSummaries() ([]string, error) {
+	if strings.HasSuffix(s.prefix, exportSummaryFile) {
+		return []string{s.prefix}, nil
+	}
+	objects, err := s.client.ListAllObjects(s.bucket, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed listing export %s %s", s.prefix, err.Error())
+	}
+	var summaries []string
+	for _, o := range objects {
+		if key := aws.StringValue(o.Key); strings.HasSuffix(key, "/"+exportSummaryFile) || key == exportSummaryFile {
+			summaries = append(summaries, key)
+		}
+	}
+	return summaries, nil
+}
+
+// Open manifest keys are full keys in the export bucket
+func (s *s3ExportSource) Open(summary, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(s.bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Body, nil
+}
+
+type localExportSource struct {
+	location string
+}
+
+func (s *localExportSource) ListSummaries() ([]string, error) {
+	var summaries []string
+	err := filepath.WalkDir(s.location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && d.Name() == exportSummaryFile {
+			summaries = append(summaries, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed listing export %s %s", s.location, err.Error())
+	}
+	return summaries, nil
+}
+
+// Open manifest keys are s3 keys (prefix/AWSDynamoDB/export-id/data/file.json.gz),
+// the local copy keeps only the tail of the key so every suffix is tried under the export dir
+func (s *localExportSource) Open(summary, key string) (io.ReadCloser, error) {
+	if key == summary {
+		return os.Open(summary)
+	}
+	exportDir := filepath.Dir(summary)
+	parts := strings.Split(key, "/")
+	for idx := range parts {
+		candidate := filepath.Join(append([]string{exportDir}, parts[idx:]...)...)
+		if f, err := os.Open(candidate); err == nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("export file %s not found under %s", key, exportDir)
+}
+
+func readExportSummary(source ExportSource, summaryKey string) (*ExportSummary, error) {
+	r, err := source.Open(summaryKey, summaryKey)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	summary := &ExportSummary{}
+	if err := json.NewDecoder(r).Decode(summary); err != nil {
+		return nil, fmt.Errorf("failed parsing export summary %s %s", summaryKey, err.Error())
+	}
+	return summary, nil
+}
+
+func readExportDataFiles(source ExportSource, summaryKey string, summary *ExportSummary) ([]*ExportDataFile, error) {
+	r, err := source.Open(summaryKey, summary.ManifestFilesS3Key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var files []*ExportDataFile
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		f := &ExportDataFile{}
+		if err := dec.Decode(f); err != nil {
+			return nil, fmt.Errorf("failed parsing export manifest %s %s", summary.ManifestFilesS3Key, err.Error())
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// newExportReader data files are gzipped, uncompressed copies are read as is, closing it does not close r
+func newExportReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(gzipMagic))
+	if bytes.Equal(magic, gzipMagic) {
+		return gzip.NewReader(br)
+	}
+	return io.NopCloser(br), nil
+}
+
+// exportFileTask a data file and the table it was exported from
+type exportFileTask struct {
+	summaryKey string
+	table      string
+	file       *ExportDataFile
+}
+
+// SearchExport searches DynamoDB JSON exports with the same parser and matcher as table scans, no read capacity is consumed
+func (s *DefaultSearcher[CC, Matcher]) SearchExport(source ExportSource, i *Input) (*Output, error) {
+	summaries, err := source.ListSummaries()
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, fmt.Errorf("no %s found in export location", exportSummaryFile)
+	}
+
+	var tasks []*exportFileTask
+	var unsupported []string
+	for _, summaryKey := range summaries {
+		summary, err := readExportSummary(source, summaryKey)
+		if err != nil {
+			return nil, err
+		}
+		lg := log.WithFields(log.Fields{"export": summary.ExportArn, "table": summary.TableName()})
+		if i.TableNamePattern != "" {
+			match, err := s.Comparator.IsMatch(i.TableNamePattern, summary.TableName())
+			if err != nil {
+				return nil, err
+			}
+			if !match {
+				lg.Debug("skipping export of table not matching pattern")
+				continue
+			}
+		}
+		// ION exports are skipped so they do not fail the search of the other exports
+		if summary.ExportFormat != "" && summary.ExportFormat != ExportFormatDynamoDBJSON {
+			lg.WithField("format", summary.ExportFormat).Warnf("skipping export, only %s exports are supported", ExportFormatDynamoDBJSON)
+			unsupported = append(unsupported, summaryKey)
+			continue
+		}
+		files, err := readExportDataFiles(source, summaryKey, summary)
+		if err != nil {
+			return nil, err
+		}
+		lg.WithField("files", len(files)).Debug("searching export")
+		for _, f := range files {
+			tasks = append(tasks, &exportFileTask{summaryKey: summaryKey, table: summary.TableName(), file: f})
+		}
+	}
+
+	if len(unsupported) > 0 && len(unsupported) == len(summaries) {
+		return nil, fmt.Errorf("no supported export found, %d exports skipped only %s format is supported", len(unsupported), ExportFormatDynamoDBJSON)
+	}
+
+	output := &Output{}
+	if len(tasks) == 0 {
+		return output, nil
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	stop := false
+	workersNum := math.Min(float64(len(tasks)), float64(i.Parallel))
+	pool := workPool.NewWorkerPool(int(workersNum))
+	for _, task := range tasks {
+		task := task
+		pool.Submit(func() {
+			mu.Lock()
+			stopped := stop
+			mu.Unlock()
+			if stopped {
+				return
+			}
+			lg := log.WithFields(log.Fields{"table": task.table, "file": task.file.DataFileS3Key})
+			err := s.searchExportFile(source, task, i, lg, func(hit *OutputHit) bool {
+				mu.Lock()
+				defer mu.Unlock()
+				if stop {
+					return false
+				}
+				output.Matches = append(output.Matches, hit)
+				stop = i.StopFirstMatch
+				return !stop
+			})
+			if err != nil {
+				lg.WithError(err).Error("failed searching export file")
+				mu.Lock()
+				errs = append(errs, err)
+				if i.FailFast {
+					stop = true
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	pool.RunAll()
+
+	if len(errs) > 0 && (i.FailFast || len(errs) == len(tasks)) {
+		return nil, errs[0]
+	}
+	return output, nil
+}
+
+// searchExportFile onHit returning false stops reading the file
+func (s *DefaultSearcher[CC, Matcher]) searchExportFile(source ExportSource, task *exportFileTask, i *Input, lg *log.Entry, onHit func(*OutputHit) bool) error {
+	r, err := source.Open(task.summaryKey, task.file.DataFileS3Key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	data, err := newExportReader(r)
+	if err != nil {
+		return fmt.Errorf("failed reading export file %s %s", task.file.DataFileS3Key, err.Error())
+	}
+	defer data.Close()
+
+	parser := s.newTableParser(i, task.table, nil)
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(make([]byte, 64*1024), maxExportLineLength)
+	for scanner.Scan() {
+		line := &exportLine{}
+		if err := json.Unmarshal(scanner.Bytes(), line); err != nil {
+			return fmt.Errorf("failed parsing export item %s %s", task.file.DataFileS3Key, err.Error())
+		}
+		item := line.Item
+		if item == nil {
+			item = line.NewImage
+		}
+		if item == nil {
+			continue
+		}
+		parsed, err := parser.Parse(item)
+		if err != nil {
+			lg.WithError(err).Warningf("error parsing object to string %#v", item)
+			continue
+		}
+		matches, err := s.SearchSingleObject(i, parsed.Values, lg)
+		if err != nil {
+			lg.WithError(err).Warningf("error searching matches in object %#v", item)
+			continue
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if !onHit(newOutputHit(task.table, i.Match, parsed, matches)) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed reading export file %s %s", task.file.DataFileS3Key, err.Error())
+	}
+	return nil
+}
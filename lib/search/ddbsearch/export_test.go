@@ -0,0 +1,72 @@
+package ddbsearch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsu "github.com/isan-rivkin/surf/lib/awsu"
+	common "github.com/isan-rivkin/surf/lib/search"
+	"github.com/magiconair/properties/assert"
+)
+
+const (
+	testExportSummary = `{"version":"2020-06-30","exportArn":"arn:aws:dynamodb:us-east-1:123456789012:table/orders/export/01","tableArn":"arn:aws:dynamodb:us-east-1:123456789012:table/orders","exportFormat":"DYNAMODB_JSON","itemCount":3,"manifestFilesS3Key":"exports/AWSDynamoDB/01/manifest-files.json"}`
+	testExportFiles   = `{"itemCount":2,"dataFileS3Key":"exports/AWSDynamoDB/01/data/a.json.gz"}
+{"itemCount":1,"dataFileS3Key":"exports/AWSDynamoDB/01/data/b.json.gz"}
+`
+	testExportDataA = `{"Item":{"pk":{"S":"order-1"},"customer":{"M":{"email":{"S":"dana@example.com"}}}}}
+{"Item":{"pk":{"S":"order-2"},"amount":{"N":"10"}}}
+`
+	testExportDataB = `{"Item":{"pk":{"S":"order-3"},"tags":{"SS":["vip","example.com"]}}}
+`
+)
+
+func TestSearchExport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "AWSDynamoDB", "01")
+	assert.Equal(t, os.MkdirAll(filepath.Join(dir, "data"), 0755), nil)
+	files := map[string][]byte{
+		exportSummaryFile:     []byte(testExportSummary),
+		"manifest-files.json": []byte(testExportFiles),
+		"data/a.json.gz":      gzipBytes(t, []byte(testExportDataA)),
+		// uncompressed copies are read as is
+		"data/b.json.gz": []byte(testExportDataB),
+	}
+	for name, content := range files {
+		assert.Equal(t, os.WriteFile(filepath.Join(dir, name), content, 0644), nil)
+	}
+
+	source, err := NewExportSource(filepath.Dir(filepath.Dir(dir)), nil)
+	assert.Equal(t, err, nil)
+	s := &DefaultSearcher[awsu.DDBApi, common.Matcher]{Comparator: common.NewDefaultRegexMatcher(), Parser: NewParserFactory()}
+
+	out, err := s.SearchExport(source, &Input{Value: "example.com", Match: ObjectMatch, Parallel: 2})
+	assert.Equal(t, err, nil)
+	paths := map[string]string{}
+	for _, m := range out.Matches {
+		assert.Equal(t, m.TableName, "orders")
+		paths[aws.StringValue(m.ObjectData["pk"])] = m.MatchedPath
+	}
+	assert.Equal(t, paths, map[string]string{"order-1": "customer.email", "order-3": "tags[1]"})
+
+	out, err = s.SearchExport(source, &Input{Value: "order", TableNamePattern: "^users$", Match: ObjectMatch, Parallel: 2})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(out.Matches), 0)
+
+	// ION exports are skipped, the other exports are still searched
+	ionDir := filepath.Join(filepath.Dir(dir), "02")
+	assert.Equal(t, os.MkdirAll(ionDir, 0755), nil)
+	ionSummary := strings.Replace(testExportSummary, "DYNAMODB_JSON", "ION", 1)
+	assert.Equal(t, os.WriteFile(filepath.Join(ionDir, exportSummaryFile), []byte(ionSummary), 0644), nil)
+	out, err = s.SearchExport(source, &Input{Value: "example.com", Match: ObjectMatch, Parallel: 2})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(out.Matches), 2)
+
+	// only unsupported exports is an error
+	ionSource, err := NewExportSource(ionDir, nil)
+	assert.Equal(t, err, nil)
+	_, err = s.SearchExport(ionSource, &Input{Value: "example.com", Match: ObjectMatch, Parallel: 2})
+	assert.Equal(t, err != nil, true)
+}
@@ -1,6 +1,12 @@
 package ddbsearch
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	awsu "github.com/isan-rivkin/surf/lib/awsu"
+	protoutil "github.com/isan-rivkin/surf/lib/common/proto"
+)
 
 type MatchLevel string
 
@@ -30,6 +36,26 @@ type Input struct {
 	Match MatchLevel
 	// Stop on first match
 	StopFirstMatch bool
+	// parallel scan segments per table, <= 1 scans each table sequentially
+	Segments int
+	// if set tables are not scanned, items are fetched by key and matched against Value
+	KeyLookup *KeyLookup
+	// if set only this attribute is matched against Value
+	Attribute string
+	// pushed down to the scan as a FilterExpression on Attribute
+	Filter *awsu.DDBFilter
+	// if set binary attributes listed in ProtoTypes are decoded with the message descriptors
+	ProtoDescriptors *protoutil.Descriptors
+	// "table:attribute" or "attribute" (any table) to the full message name i.e shop.v1.Order
+	ProtoTypes map[string]string
+	// max nested encodings (compression, msgpack, cbor, base64) unwrapped per attribute, 0 disables decoding
+	DecodeDepth int
+	// scans are throttled to this read capacity per second across all tables, 0 is unlimited
+	MaxRCUPerSecond float64
+	// scans stop once this read capacity was consumed across all tables, 0 is unlimited
+	MaxTotalRCU float64
+	// if set scan progress and hits are saved to it and scans continue from its saved keys
+	Checkpoint *Checkpoint
 }
 
 func NewSearchInput(table, query string, failFast, withGlobalTables, stopFirstMatch bool, match MatchLevel, parallel int) (*Input, error) {
@@ -49,5 +75,59 @@ func NewSearchInput(table, query string, failFast, withGlobalTables, stopFirstMa
 		WithGlobalTables: withGlobalTables,
 		Match:            match,
 		StopFirstMatch:   stopFirstMatch,
+		DecodeDepth:      DefaultDecodeDepth,
 	}, nil
 }
+
+func (i *Input) WithSegments(segments int) *Input {
+	i.Segments = segments
+	return i
+}
+
+// WithAttribute scopes matching to a single attribute and optionally pushes an operator down to DynamoDB
+func (i *Input) WithAttribute(attribute string, operator awsu.DDBFilterOperator, value string) *Input {
+	i.Attribute = attribute
+	if operator != "" {
+		i.Filter = &awsu.DDBFilter{Attribute: attribute, Operator: operator, Value: value}
+	}
+	return i
+}
+
+func (i *Input) WithCapacityBudget(maxRCUPerSecond, maxTotalRCU float64) *Input {
+	i.MaxRCUPerSecond = maxRCUPerSecond
+	i.MaxTotalRCU = maxTotalRCU
+	return i
+}
+
+func (i *Input) WithDecodeDepth(depth int) *Input {
+	i.DecodeDepth = depth
+	return i
+}
+
+// WithProtoDescriptors every message type in types must exist in the descriptors
+func (i *Input) WithProtoDescriptors(descriptors *protoutil.Descriptors, types map[string]string) (*Input, error) {
+	for attr, messageName := range types {
+		if !descriptors.HasMessage(messageName) {
+			return nil, fmt.Errorf("message type %s for %s not found in proto descriptors", messageName, attr)
+		}
+	}
+	i.ProtoDescriptors = descriptors
+	i.ProtoTypes = types
+	return i, nil
+}
+
+// ProtoTypesForTable attribute to message name for the table, table specific mappings take precedence
+func (i *Input) ProtoTypesForTable(table string) map[string]string {
+	types := map[string]string{}
+	for key, messageName := range i.ProtoTypes {
+		if !strings.Contains(key, ":") {
+			types[key] = messageName
+		}
+	}
+	for key, messageName := range i.ProtoTypes {
+		if parts := strings.SplitN(key, ":", 2); len(parts) == 2 && parts[0] == table {
+			types[parts[1]] = messageName
+		}
+	}
+	return types
+}
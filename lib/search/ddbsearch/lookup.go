@@ -0,0 +1,150 @@
+package ddbsearch
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	awsu "github.com/isan-rivkin/surf/lib/awsu"
+	log "github.com/sirupsen/logrus"
+)
+
+// KeyLookup replaces the table scan with GetItem / Query on the primary key and chosen indexes
+type KeyLookup struct {
+	PartitionValue string
+	// optional sort key value, exact match unless SortBeginsWith
+	SortValue      string
+	SortBeginsWith bool
+	// also query secondary indexes whose partition key attribute is one of these
+	IndexAttributes []string
+}
+
+func (i *Input) WithKeyLookup(lookup *KeyLookup) *Input {
+	i.KeyLookup = lookup
+	return i
+}
+
+// buildKeyConditions creates a condition for the primary key and every index keyed by one of the lookup attributes.
+// indexes whose key types do not fit the values (i.e a number key and a text value) are skipped
+func buildKeyConditions(table string, indexes []*awsu.DDBIndexSchema, lookup *KeyLookup) []*awsu.DDBKeyCondition {
+	indexAttrs := map[string]bool{}
+	for _, a := range lookup.IndexAttributes {
+		indexAttrs[a] = true
+	}
+	var conds []*awsu.DDBKeyCondition
+	for _, idx := range indexes {
+		isPrimary := idx.IndexName == ""
+		if idx.HashKey == nil || (!isPrimary && !indexAttrs[idx.HashKey.Name]) {
+			continue
+		}
+		lg := log.WithFields(log.Fields{"table": table, "index": idx.IndexName})
+		hashVal, err := awsu.NewKeyAttributeValue(idx.HashKey.KeyType, lookup.PartitionValue)
+		if err != nil {
+			lg.WithError(err).Debug("skipping index lookup")
+			continue
+		}
+		cond := &awsu.DDBKeyCondition{Table: table, Index: idx, HashValue: hashVal, RangeBeginsWith: lookup.SortBeginsWith}
+		if lookup.SortValue != "" && idx.RangeKey != nil {
+			if cond.RangeValue, err = awsu.NewKeyAttributeValue(idx.RangeKey.KeyType, lookup.SortValue); err != nil {
+				lg.WithError(err).Debug("skipping index lookup")
+				continue
+			}
+		}
+		conds = append(conds, cond)
+	}
+	return conds
+}
+
+// isGetItem a primary key lookup with the full key can use GetItem
+func isGetItem(cond *awsu.DDBKeyCondition) bool {
+	if cond.Index.IndexName != "" {
+		return false
+	}
+	if cond.Index.RangeKey == nil {
+		return true
+	}
+	return cond.RangeValue != nil && !cond.RangeBeginsWith
+}
+
+func primaryKeyID(item map[string]*dynamodb.AttributeValue, primary *awsu.DDBIndexSchema) string {
+	id := item[primary.HashKey.Name].GoString()
+	if primary.RangeKey != nil {
+		id += "|" + item[primary.RangeKey.Name].GoString()
+	}
+	return id
+}
+
+// LookupTableData fetches items by key instead of scanning, items fetched from several indexes are returned once.
+// the lookups are throttled by the budget and stop once it is exhausted
+func (s *DefaultSearcher[CC, Matcher]) LookupTableData(desc awsu.DDBTableDescriber, input *Input, parser ObjParser, budget *awsu.DDBCapacityBudget, lg *log.Entry) ([]*OutputHit, error) {
+	indexes, err := desc.GetIndexSchemas()
+	if err != nil {
+		return nil, err
+	}
+	name := desc.TableName()
+	conds := buildKeyConditions(name, indexes, input.KeyLookup)
+	if len(conds) == 0 {
+		lg.Debug("no key or index fits the lookup values")
+		return nil, nil
+	}
+
+	var hits []*OutputHit
+	seen := map[string]bool{}
+	stop := false
+	handleItems := func(items []map[string]*dynamodb.AttributeValue) bool {
+		for _, item := range items {
+			id := primaryKeyID(item, indexes[0])
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			parsed, err := parser.Parse(item)
+			if err != nil {
+				lg.WithError(err).Warningf("error parsing object to string %#v", item)
+				continue
+			}
+			matches, err := s.SearchSingleObject(input, parsed.Values, lg)
+			if err != nil {
+				lg.WithError(err).Warningf("error searching matches in object %#v", item)
+				continue
+			}
+			if len(matches) > 0 {
+				hits = append(hits, newOutputHit(name, input.Match, parsed, matches))
+				if input.StopFirstMatch {
+					stop = true
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	for _, cond := range conds {
+		if stop {
+			break
+		}
+		if budget != nil && budget.Exhausted() {
+			lg.Debug("stopping key lookup, read capacity budget exhausted")
+			break
+		}
+		lg.WithFields(log.Fields{"index": cond.Index.IndexName, "get_item": isGetItem(cond)}).Debug("key lookup")
+		if isGetItem(cond) {
+			key := map[string]*dynamodb.AttributeValue{cond.Index.HashKey.Name: cond.HashValue}
+			if cond.Index.RangeKey != nil {
+				key[cond.Index.RangeKey.Name] = cond.RangeValue
+			}
+			item, err := s.Client.GetItem(name, key, budget)
+			if err != nil {
+				return hits, err
+			}
+			if item != nil {
+				handleItems([]map[string]*dynamodb.AttributeValue{item})
+			}
+			continue
+		}
+		cond.Budget = budget
+		if err := s.Client.QueryTable(cond, handleItems); err != nil {
+			return hits, fmt.Errorf("failed key lookup %s", err.Error())
+		}
+	}
+	return hits, nil
+}
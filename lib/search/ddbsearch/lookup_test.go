@@ -0,0 +1,166 @@
+package ddbsearch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	awsu "github.com/isan-rivkin/surf/lib/awsu"
+	common "github.com/isan-rivkin/surf/lib/search"
+	"github.com/magiconair/properties/assert"
+	log "github.com/sirupsen/logrus"
+)
+
+// fakeLookupDDB serves GetItem and Query (one item per page) from in memory items
+type fakeLookupDDB struct {
+	awsu.DDBApi
+	items   []map[string]*dynamodb.AttributeValue
+	calls   []string
+	indexes []*awsu.DDBIndexSchema
+}
+
+type fakeLookupTable struct {
+	awsu.DDBTableDescriber
+	indexes []*awsu.DDBIndexSchema
+}
+
+func (f *fakeLookupTable) TableName() string {
+	return "orders"
+}
+
+func (f *fakeLookupTable) GetIndexSchemas() ([]*awsu.DDBIndexSchema, error) {
+	return f.indexes, nil
+}
+
+func (f *fakeLookupDDB) GetItem(table string, key map[string]*dynamodb.AttributeValue, budget *awsu.DDBCapacityBudget) (map[string]*dynamodb.AttributeValue, error) {
+	f.calls = append(f.calls, "get")
+	if budget != nil {
+		budget.Consume(table, 1)
+	}
+	for _, item := range f.items {
+		found := true
+		for k, v := range key {
+			found = found && aws.StringValue(item[k].S) == aws.StringValue(v.S)
+		}
+		if found {
+			return item, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeLookupDDB) QueryTable(cond *awsu.DDBKeyCondition, pageHandler awsu.DDBAttributesHandler) error {
+	f.calls = append(f.calls, "query:"+cond.Index.IndexName)
+	for _, item := range f.items {
+		if aws.StringValue(item[cond.Index.HashKey.Name].S) != aws.StringValue(cond.HashValue.S) {
+			continue
+		}
+		if cond.RangeValue != nil {
+			sortVal := aws.StringValue(item[cond.Index.RangeKey.Name].S)
+			if cond.RangeBeginsWith && !strings.HasPrefix(sortVal, aws.StringValue(cond.RangeValue.S)) {
+				continue
+			}
+			if !cond.RangeBeginsWith && sortVal != aws.StringValue(cond.RangeValue.S) {
+				continue
+			}
+		}
+		if cond.Budget != nil {
+			cond.Budget.Consume(cond.Table, 1)
+		}
+		if !pageHandler([]map[string]*dynamodb.AttributeValue{item}) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func newFakeLookupDDB() *fakeLookupDDB {
+	order := func(pk, sk, customer string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{
+			"pk":          {S: aws.String(pk)},
+			"sk":          {S: aws.String(sk)},
+			"customer_id": {S: aws.String(customer)},
+		}
+	}
+	return &fakeLookupDDB{
+		indexes: []*awsu.DDBIndexSchema{
+			{HashKey: &awsu.DDBSchemaKey{Name: "pk", KeyType: "S"}, RangeKey: &awsu.DDBSchemaKey{Name: "sk", KeyType: "S"}},
+			{IndexName: "by-customer", HashKey: &awsu.DDBSchemaKey{Name: "customer_id", KeyType: "S"}},
+		},
+		items: []map[string]*dynamodb.AttributeValue{
+			order("cust-1", "2023-01", "cust-1"),
+			order("cust-1", "2023-02", "cust-1"),
+			order("order-9", "2022-12", "cust-1"),
+			order("cust-2", "2023-01", "cust-2"),
+		},
+	}
+}
+
+func TestBuildKeyConditions(t *testing.T) {
+	indexes := []*awsu.DDBIndexSchema{
+		{HashKey: &awsu.DDBSchemaKey{Name: "pk", KeyType: "S"}, RangeKey: &awsu.DDBSchemaKey{Name: "sk", KeyType: "S"}},
+		{IndexName: "by-customer", HashKey: &awsu.DDBSchemaKey{Name: "customer_id", KeyType: "S"}},
+		{IndexName: "by-amount", HashKey: &awsu.DDBSchemaKey{Name: "amount", KeyType: "N"}},
+	}
+
+	conds := buildKeyConditions("orders", indexes, &KeyLookup{PartitionValue: "cust-1"})
+	assert.Equal(t, len(conds), 1)
+	// no sort key value, the primary key has a sort key so it must be a query
+	assert.Equal(t, isGetItem(conds[0]), false)
+
+	conds = buildKeyConditions("orders", indexes, &KeyLookup{PartitionValue: "cust-1", SortValue: "2023", IndexAttributes: []string{"customer_id", "amount"}})
+	// amount is a number index and the value is not a number
+	assert.Equal(t, len(conds), 2)
+	assert.Equal(t, isGetItem(conds[0]), true)
+	assert.Equal(t, conds[1].Index.IndexName, "by-customer")
+	assert.Equal(t, isGetItem(conds[1]), false)
+
+	conds = buildKeyConditions("orders", indexes, &KeyLookup{PartitionValue: "cust-1", SortValue: "2023", SortBeginsWith: true})
+	assert.Equal(t, isGetItem(conds[0]), false)
+}
+
+func TestLookupTableData(t *testing.T) {
+	s := &DefaultSearcher[awsu.DDBApi, common.Matcher]{Comparator: common.NewDefaultRegexMatcher(), Parser: NewParserFactory()}
+	lg := log.WithField("test", t.Name())
+	lookup := func(db *fakeLookupDDB, l *KeyLookup, stopFirst bool, budget *awsu.DDBCapacityBudget) []*OutputHit {
+		s.Client = db
+		i := &Input{Value: "cust-1", Match: ObjectMatch, StopFirstMatch: stopFirst, KeyLookup: l}
+		hits, err := s.LookupTableData(&fakeLookupTable{indexes: db.indexes}, i, s.newTableParser(i, "orders", nil), budget, lg)
+		assert.Equal(t, err, nil)
+		return hits
+	}
+
+	// full primary key is a GetItem
+	db := newFakeLookupDDB()
+	hits := lookup(db, &KeyLookup{PartitionValue: "cust-1", SortValue: "2023-02"}, false, nil)
+	assert.Equal(t, db.calls, []string{"get"})
+	assert.Equal(t, len(hits), 1)
+	assert.Equal(t, aws.StringValue(hits[0].ObjectData["sk"]), "2023-02")
+
+	// sort key prefix is a Query
+	db = newFakeLookupDDB()
+	hits = lookup(db, &KeyLookup{PartitionValue: "cust-1", SortValue: "2023", SortBeginsWith: true}, false, nil)
+	assert.Equal(t, db.calls, []string{"query:"})
+	assert.Equal(t, len(hits), 2)
+
+	// items found by the primary key and the index are returned once
+	db = newFakeLookupDDB()
+	hits = lookup(db, &KeyLookup{PartitionValue: "cust-1", IndexAttributes: []string{"customer_id"}}, false, nil)
+	assert.Equal(t, db.calls, []string{"query:", "query:by-customer"})
+	assert.Equal(t, len(hits), 3)
+
+	// stop on the first match skips the remaining pages and indexes
+	db = newFakeLookupDDB()
+	hits = lookup(db, &KeyLookup{PartitionValue: "cust-1", IndexAttributes: []string{"customer_id"}}, true, nil)
+	assert.Equal(t, db.calls, []string{"query:"})
+	assert.Equal(t, len(hits), 1)
+
+	// lookups stop once the read capacity budget is exhausted
+	db = newFakeLookupDDB()
+	budget := awsu.NewDDBCapacityBudget(0, 1)
+	hits = lookup(db, &KeyLookup{PartitionValue: "cust-1", IndexAttributes: []string{"customer_id"}}, false, budget)
+	assert.Equal(t, db.calls, []string{"query:"})
+	assert.Equal(t, len(hits), 2)
+	assert.Equal(t, budget.ConsumedByTable()["orders"], 2.0)
+}
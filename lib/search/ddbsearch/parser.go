@@ -1,87 +1,202 @@
 package ddbsearch
 
 import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	ddbAttr "github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	awsu "github.com/isan-rivkin/surf/lib/awsu"
 	protoutil "github.com/isan-rivkin/surf/lib/common/proto"
+	log "github.com/sirupsen/logrus"
 )
 
-type ParserOpt func(tableObj map[string]*dynamodb.AttributeValue, searchObj map[string]*string)
+// ParsedObject the searchable string values of an item by attribute path, DecodedBy is the decoder chain of decoded attributes i.e base64>gzip
+type ParsedObject struct {
+	Values    map[string]*string
+	DecodedBy map[string]string
+}
+
+// DecodedByPath the decoder chain of the top level attribute the path belongs to
+func (p *ParsedObject) DecodedByPath(path string) string {
+	if strings.HasSuffix(path, RawPathSuffix) {
+		return ""
+	}
+	if chain, exist := p.DecodedBy[path]; exist {
+		return chain
+	}
+	if idx := strings.IndexAny(path, ".["); idx > 0 {
+		return p.DecodedBy[path[:idx]]
+	}
+	return ""
+}
+
+type ParserOpt func(tableObj map[string]*dynamodb.AttributeValue, parsed *ParsedObject)
 
-// TODO this is a weak parser since itll present false positives it will parse the wrapper that the sdk ads arround the objects not only the pure data
-func WithFmtGoString(schemas map[string]*awsu.DDBSchemaKey, onlySchemaKeys bool, overrideIfExist bool) ParserOpt {
-	skipIfVal := []string{"<binary>", "<invalid value>"}
-	return func(tableObj map[string]*dynamodb.AttributeValue, searchObj map[string]*string) {
+// WithFmtTyped flattens every attribute by its type into dotted paths (address.city, tags[2]) with plain string values,
+// so matching is done on the data itself and not on the sdk wrapper
+func WithFmtTyped(schemas map[string]*awsu.DDBSchemaKey, onlySchemaKeys bool, overrideIfExist bool) ParserOpt {
+	return func(tableObj map[string]*dynamodb.AttributeValue, parsed *ParsedObject) {
 		for k, v := range tableObj {
 			_, isSchema := schemas[k]
-			if (isSchema && onlySchemaKeys) || !onlySchemaKeys {
-				if _, exist := searchObj[k]; !exist || overrideIfExist {
-					isNil := v.NULL != nil && *v.NULL == false
-					if !isNil {
-						if valStr := v.GoString(); valStr != "" {
-							containsSkipVals := false
-							for _, skipStr := range skipIfVal {
-								if strings.Contains(valStr, skipStr) {
-									containsSkipVals = true
-									break
-								}
-							}
-							if !containsSkipVals {
-								searchObj[k] = &valStr
-							} else if v.B != nil {
-								str := string(v.B)
-								searchObj[k] = &str
-							}
-						}
-					}
-				}
+			if onlySchemaKeys && !isSchema {
+				continue
+			}
+			if _, exist := parsed.Values[k]; exist && !overrideIfExist {
+				continue
+			}
+			for path, val := range FlattenAttribute(k, v) {
+				val := val
+				parsed.Values[path] = &val
 			}
 		}
 	}
 }
 
+// FlattenAttribute walks M/L/SS/NS/BS recursively, map keys are joined with "." and list items with [index]
+func FlattenAttribute(path string, v *dynamodb.AttributeValue) map[string]string {
+	flat := map[string]string{}
+	flattenAttribute(path, v, flat)
+	return flat
+}
+
+func flattenAttribute(path string, v *dynamodb.AttributeValue, flat map[string]string) {
+	if v == nil {
+		return
+	}
+	switch {
+	case v.S != nil:
+		flat[path] = *v.S
+	case v.N != nil:
+		flat[path] = *v.N
+	case v.BOOL != nil:
+		flat[path] = strconv.FormatBool(*v.BOOL)
+	case v.NULL != nil && *v.NULL:
+		flat[path] = "null"
+	case v.B != nil:
+		flat[path] = binaryToString(v.B)
+	case v.M != nil:
+		if len(v.M) == 0 {
+			flat[path] = "{}"
+		}
+		for k, child := range v.M {
+			flattenAttribute(path+"."+k, child, flat)
+		}
+	case v.L != nil:
+		if len(v.L) == 0 {
+			flat[path] = "[]"
+		}
+		for idx, child := range v.L {
+			flattenAttribute(fmt.Sprintf("%s[%d]", path, idx), child, flat)
+		}
+	case v.SS != nil:
+		for idx, item := range v.SS {
+			flat[fmt.Sprintf("%s[%d]", path, idx)] = aws.StringValue(item)
+		}
+	case v.NS != nil:
+		for idx, item := range v.NS {
+			flat[fmt.Sprintf("%s[%d]", path, idx)] = aws.StringValue(item)
+		}
+	case v.BS != nil:
+		for idx, item := range v.BS {
+			flat[fmt.Sprintf("%s[%d]", path, idx)] = binaryToString(item)
+		}
+	}
+}
+
+// binaryToString keeps text stored as binary searchable, anything else is base64 like the DynamoDB console shows it
+func binaryToString(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// IsAttributePath true if path is the attribute itself or nested under it
+func IsAttributePath(path, attribute string) bool {
+	if !strings.HasPrefix(path, attribute) {
+		return false
+	}
+	rest := path[len(attribute):]
+	return rest == "" || strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "[") || strings.HasPrefix(rest, RawPathSuffix)
+}
+
+// WithFmtProto decodes binary attributes without a schema into a field number dump, binary text is left to other parsers
 func WithFmtProto(schemas map[string]*awsu.DDBSchemaKey, includeSchemaKeys bool, overrideIfExist bool, delimeter string) ParserOpt {
-	return func(tableObj map[string]*dynamodb.AttributeValue, searchObj map[string]*string) {
+	return func(tableObj map[string]*dynamodb.AttributeValue, parsed *ParsedObject) {
 		for k, v := range tableObj {
-			// if not binary format s
-			if len(v.B) == 0 && len(v.BS) == 0 {
-				return
+			if !isProtoCandidate(k, v, schemas, includeSchemaKeys, overrideIfExist, parsed.Values) {
+				continue
 			}
-			// if already exist and not override
-			if _, exist := searchObj[k]; exist && !overrideIfExist {
-				return
+			var dumps []string
+			for _, bs := range binaryValues(v) {
+				if protoutil.IsPrintableText(bs) {
+					break
+				}
+				dump, ok := protoutil.Dump(bs)
+				if !ok {
+					break
+				}
+				dumps = append(dumps, dump)
 			}
-
-			// if key should be skipped proto parsing
-			_, isSchema := schemas[k]
-			if !includeSchemaKeys && isSchema {
-				return
+			// all values must be protobuf
+			if len(dumps) > 0 && len(dumps) == len(binaryValues(v)) {
+				strVal := strings.Join(dumps, delimeter)
+				parsed.Values[k] = &strVal
 			}
-			// try parse obj
-			var bytesSet [][]byte
-			if len(v.B) > 0 {
-				bytesSet = append(bytesSet, v.B)
-			} else if len(v.BS) > 0 {
-				bytesSet = v.BS
+		}
+	}
+}
+
+// WithFmtProtoDescriptors decodes binary attributes into json with field names, messageTypes maps attribute name to the full message name
+func WithFmtProtoDescriptors(descriptors *protoutil.Descriptors, messageTypes map[string]string, overrideIfExist bool, delimeter string) ParserOpt {
+	return func(tableObj map[string]*dynamodb.AttributeValue, parsed *ParsedObject) {
+		for k, v := range tableObj {
+			messageName, exist := messageTypes[k]
+			if !exist || !isProtoCandidate(k, v, nil, true, overrideIfExist, parsed.Values) {
+				continue
 			}
-			accumulated := &protoutil.Accomulator{}
-			for _, bs := range bytesSet {
-				_ = protoutil.ParseUnknown(bs, accumulated)
-				if !accumulated.IsProtoPayload() {
-					return
+			var decoded []string
+			for _, bs := range binaryValues(v) {
+				j, err := descriptors.DecodeToJSON(messageName, bs)
+				if err != nil {
+					log.WithError(err).WithField("attribute", k).Debug("failed decoding attribute with descriptor")
+					break
 				}
+				decoded = append(decoded, j)
 			}
-			if accumulated.IsProtoPayload() {
-				strVal := accumulated.ToString(delimeter)
-				searchObj[k] = &strVal
+			if len(decoded) > 0 && len(decoded) == len(binaryValues(v)) {
+				strVal := strings.Join(decoded, delimeter)
+				parsed.Values[k] = &strVal
 			}
 		}
 	}
 }
 
+func isProtoCandidate(k string, v *dynamodb.AttributeValue, schemas map[string]*awsu.DDBSchemaKey, includeSchemaKeys, overrideIfExist bool, searchObj map[string]*string) bool {
+	// if not binary format
+	if len(v.B) == 0 && len(v.BS) == 0 {
+		return false
+	}
+	// if already exist and not override
+	if _, exist := searchObj[k]; exist && !overrideIfExist {
+		return false
+	}
+	// if key should be skipped proto parsing
+	_, isSchema := schemas[k]
+	return includeSchemaKeys || !isSchema
+}
+
+func binaryValues(v *dynamodb.AttributeValue) [][]byte {
+	if len(v.B) > 0 {
+		return [][]byte{v.B}
+	}
+	return v.BS
+}
+
 type ObjParserFactory interface {
 	New(opts ...ParserOpt) ObjParser
 }
@@ -100,6 +215,7 @@ func (f *ParserFactory) New(opts ...ParserOpt) ObjParser {
 }
 
 type ObjParser interface {
+	Parse(tableObj map[string]*dynamodb.AttributeValue) (*ParsedObject, error)
 	ParseToStrings(tableObj map[string]*dynamodb.AttributeValue) (map[string]*string, error)
 }
 type ObjDefaultParser struct {
@@ -110,29 +226,20 @@ func NewObjDefaultParser(opts ...ParserOpt) ObjParser {
 	return &ObjDefaultParser{opts: opts}
 }
 
-func (p *ObjDefaultParser) ParseToStrings(tableObj map[string]*dynamodb.AttributeValue) (map[string]*string, error) {
-	searchObj := map[string]*string{}
+func (p *ObjDefaultParser) Parse(tableObj map[string]*dynamodb.AttributeValue) (*ParsedObject, error) {
+	parsed := &ParsedObject{Values: map[string]*string{}, DecodedBy: map[string]string{}}
 
 	for _, opt := range p.opts {
-		opt(tableObj, searchObj)
+		opt(tableObj, parsed)
 	}
 
-	return searchObj, nil
+	return parsed, nil
 }
 
-// TODO: unmarshaller is more for known structs, we need string represenatation to searxh so its not a fit
-func ____WithFmtAWSMarshaller(schemas map[string]*awsu.DDBSchemaKey, onlySchemaKeys bool, overrideIfExist bool, delimeter string) ParserOpt {
-	return func(tableObj map[string]*dynamodb.AttributeValue, searchObj map[string]*string) {
-		unmarshalled := map[string]any{}
-		ddbAttr.UnmarshalMap(tableObj, unmarshalled)
-
-		for k, v := range tableObj {
-			_, isSchema := schemas[k]
-			if (isSchema && onlySchemaKeys) || !onlySchemaKeys {
-				if _, exist := searchObj[k]; !exist || overrideIfExist {
-					panic("UMARSHAL FULLY not imeplemented " + v.GoString())
-				}
-			}
-		}
+func (p *ObjDefaultParser) ParseToStrings(tableObj map[string]*dynamodb.AttributeValue) (map[string]*string, error) {
+	parsed, err := p.Parse(tableObj)
+	if err != nil {
+		return nil, err
 	}
+	return parsed.Values, nil
 }
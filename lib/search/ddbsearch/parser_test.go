@@ -0,0 +1,104 @@
+package ddbsearch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	protoutil "github.com/isan-rivkin/surf/lib/common/proto"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestFlattenAttribute(t *testing.T) {
+	item := map[string]*dynamodb.AttributeValue{
+		"id": {S: aws.String("user-1")},
+		"address": {M: map[string]*dynamodb.AttributeValue{
+			"city": {S: aws.String("Tel Aviv")},
+			"zip":  {N: aws.String("61000")},
+		}},
+		"tags":    {L: []*dynamodb.AttributeValue{{S: aws.String("a")}, {BOOL: aws.Bool(true)}, {M: map[string]*dynamodb.AttributeValue{"x": {NULL: aws.Bool(true)}}}}},
+		"roles":   {SS: []*string{aws.String("admin"), aws.String("dev")}},
+		"raw":     {B: []byte("plain text")},
+		"blob":    {B: []byte{0xff, 0xfe}},
+		"empty":   {M: map[string]*dynamodb.AttributeValue{}},
+		"nothing": {L: []*dynamodb.AttributeValue{}},
+	}
+	p := NewObjDefaultParser(WithFmtTyped(nil, false, false))
+	obj, err := p.ParseToStrings(item)
+	assert.Equal(t, err, nil)
+
+	got := map[string]string{}
+	for k, v := range obj {
+		got[k] = aws.StringValue(v)
+	}
+	assert.Equal(t, got, map[string]string{
+		"id":           "user-1",
+		"address.city": "Tel Aviv",
+		"address.zip":  "61000",
+		"tags[0]":      "a",
+		"tags[1]":      "true",
+		"tags[2].x":    "null",
+		"roles[0]":     "admin",
+		"roles[1]":     "dev",
+		"raw":          "plain text",
+		"blob":         "//4=",
+		"empty":        "{}",
+		"nothing":      "[]",
+	})
+}
+
+func TestIsAttributePath(t *testing.T) {
+	assert.Equal(t, IsAttributePath("address", "address"), true)
+	assert.Equal(t, IsAttributePath("address.city", "address"), true)
+	assert.Equal(t, IsAttributePath("tags[1]", "tags"), true)
+	assert.Equal(t, IsAttributePath("addresses", "address"), false)
+	assert.Equal(t, IsAttributePath("address.city", "address.city"), true)
+}
+
+const testOrderProto = `syntax = "proto3";
+package shop.v1;
+
+message Order {
+  string customer_id = 1;
+  int64 amount = 2;
+}
+`
+
+func TestWithFmtProtoDescriptors(t *testing.T) {
+	dir := t.TempDir()
+	protoPath := filepath.Join(dir, "order.proto")
+	assert.Equal(t, os.WriteFile(protoPath, []byte(testOrderProto), 0644), nil)
+
+	descriptors, err := protoutil.NewDescriptors([]string{"order.proto"}, []string{dir})
+	assert.Equal(t, err, nil)
+	// a path under the import path or without import paths resolves to the same file
+	for _, importPaths := range [][]string{{dir}, nil} {
+		d, err := protoutil.NewDescriptors([]string{protoPath}, importPaths)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, d.HasMessage("shop.v1.Order"), true)
+	}
+
+	// customer_id: "cust-1", amount: 150
+	payload := []byte{0x0a, 0x06, 'c', 'u', 's', 't', '-', '1', 0x10, 0x96, 0x01}
+	item := map[string]*dynamodb.AttributeValue{"payload": {B: payload}}
+
+	i, err := (&Input{}).WithProtoDescriptors(descriptors, map[string]string{"orders:payload": "shop.v1.Order"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, i.ProtoTypesForTable("users"), map[string]string{})
+
+	p := NewObjDefaultParser(
+		WithFmtProto(nil, false, true, " "),
+		WithFmtProtoDescriptors(descriptors, i.ProtoTypesForTable("orders"), true, " "),
+	)
+	obj, _ := p.ParseToStrings(item)
+	assert.Equal(t, aws.StringValue(obj["payload"]), `{"customer_id":"cust-1","amount":"150"}`)
+
+	// without descriptors the fields are dumped by number
+	obj, _ = NewObjDefaultParser(WithFmtProto(nil, false, true, " ")).ParseToStrings(item)
+	assert.Equal(t, aws.StringValue(obj["payload"]), `1: "cust-1" 2: 150`)
+
+	_, err = (&Input{}).WithProtoDescriptors(descriptors, map[string]string{"payload": "shop.v1.Missing"})
+	assert.Equal(t, err != nil, true)
+}
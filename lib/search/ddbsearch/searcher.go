@@ -3,6 +3,8 @@ package ddbsearch
 import (
 	"fmt"
 	"math"
+	"sort"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -16,6 +18,35 @@ type OutputHit struct {
 	TableName  string
 	HitLevel   MatchLevel
 	ObjectData map[string]*string
+	// the first matched attribute path i.e address.city
+	MatchedPath string
+	// decoders that produced the matched value i.e base64>gzip, empty if not decoded
+	DecodedBy string
+	// every attribute whose name or value matched
+	Matches []*AttributeMatch
+}
+
+// AttributeMatch an attribute name or value that matched the query
+type AttributeMatch struct {
+	// flattened attribute path i.e address.city
+	Path string `json:"path"`
+	// true if the attribute name matched, false if the value matched
+	IsKeyMatch bool `json:"is_key_match"`
+	// [start, end) byte offsets of the matches in the attribute name or value
+	Offsets [][]int `json:"offsets,omitempty"`
+}
+
+// newOutputHit matches must not be empty
+func newOutputHit(table string, level MatchLevel, parsed *ParsedObject, matches []*AttributeMatch) *OutputHit {
+	path := matches[0].Path
+	return &OutputHit{
+		TableName:   table,
+		HitLevel:    level,
+		ObjectData:  parsed.Values,
+		MatchedPath: path,
+		DecodedBy:   parsed.DecodedByPath(path),
+		Matches:     matches,
+	}
 }
 
 type _AsyncOutputs struct {
@@ -26,10 +57,15 @@ type _AsyncOutputs struct {
 
 type Output struct {
 	Matches []*OutputHit
+	// read capacity units consumed by scans per table
+	ConsumedRCU map[string]float64
+	// true if scans stopped because Input.MaxTotalRCU was consumed
+	BudgetExhausted bool
 }
 
 type Searcher[Client awsu.DDBApi, Matcher common.Matcher] interface {
 	Search(i *Input) (*Output, error)
+	SearchExport(source ExportSource, i *Input) (*Output, error)
 }
 
 type DefaultSearcher[Client awsu.DDBApi, Matcher common.Matcher] struct {
@@ -82,8 +118,14 @@ func (s *DefaultSearcher[CC, Matcher]) Search(i *Input) (*Output, error) {
 	if len(tablesToDescribe) == 0 {
 		return output, nil
 	}
-	// search inside tables
-	// TODO parallel search inside tables not only between tables
+	// shared by all tables scans
+	budget := awsu.NewDDBCapacityBudget(i.MaxRCUPerSecond, i.MaxTotalRCU)
+	if i.Checkpoint != nil && i.KeyLookup == nil {
+		// scan progress is saved in the background, the page handlers only record it
+		stopSaving := i.Checkpoint.SaveEvery(checkpointSaveInterval)
+		defer stopSaving()
+	}
+	// search inside tables, each table is scanned in input.Segments parallel segments
 	asyncResults := make(chan *_AsyncOutputs, len(tablesToDescribe))
 
 	workersNum := math.Min(float64(len(tablesToDescribe)), float64(i.Parallel))
@@ -107,11 +149,13 @@ func (s *DefaultSearcher[CC, Matcher]) Search(i *Input) (*Output, error) {
 					lg.WithError(err).Debug("failed while fetching schema definitions")
 					res.Err = err
 				} else {
-					p := s.Parser.New(
-						WithFmtProto(schemas, false, true, " "),
-						WithFmtGoString(schemas, false, false),
-					)
-					searchables, err := s.SearchTableData(tDescriber.TableName(), i, p, lg)
+					p := s.newTableParser(i, t.TableName(), schemas)
+					var searchables []*OutputHit
+					if i.KeyLookup != nil {
+						searchables, err = s.LookupTableData(tDescriber, i, p, budget, lg)
+					} else {
+						searchables, err = s.SearchTableData(tDescriber.TableName(), i, p, budget, lg)
+					}
 					if err != nil {
 						lg.WithError(err).Debug("failed while searching a single table data")
 						res.Err = err
@@ -144,13 +188,50 @@ func (s *DefaultSearcher[CC, Matcher]) Search(i *Input) (*Output, error) {
 
 		counter++
 	}
+	// hits of previous runs are only in the checkpoint
+	if i.Checkpoint != nil && i.KeyLookup == nil {
+		output.Matches = i.Checkpoint.AllHits()
+		if err := i.Checkpoint.Save(); err != nil {
+			log.WithError(err).Warn("failed saving scan checkpoint")
+		}
+	}
+	output.ConsumedRCU = budget.ConsumedByTable()
+	output.BudgetExhausted = budget.Exhausted()
+	if output.BudgetExhausted {
+		log.WithField("max_total_rcu", i.MaxTotalRCU).Warn("stopped scanning, read capacity budget exhausted results are partial")
+	}
 
 	return output, nil
 }
 
-func (s *DefaultSearcher[CC, Matcher]) SearchSingleObject(input *Input, obj map[string]*string, lg *log.Entry) (bool, error) {
+// newTableParser decoders first, then protobuf and the typed attributes for whatever was not decoded
+func (s *DefaultSearcher[CC, Matcher]) newTableParser(i *Input, table string, schemas map[string]*awsu.DDBSchemaKey) ObjParser {
+	var opts []ParserOpt
+	if i.DecodeDepth > 0 {
+		opts = append(opts, WithFmtDecoders(DefaultPayloadDecoders(), i.DecodeDepth, false, " "))
+	}
+	opts = append(opts, WithFmtProto(schemas, false, false, " "))
+	if i.ProtoDescriptors != nil {
+		opts = append(opts, WithFmtProtoDescriptors(i.ProtoDescriptors, i.ProtoTypesForTable(table), true, " "))
+	}
+	return s.Parser.New(append(opts, WithFmtTyped(schemas, false, false))...)
+}
+
+// SearchSingleObject returns every matched attribute name or value sorted by path, the object matched if there are any
+func (s *DefaultSearcher[CC, Matcher]) SearchSingleObject(input *Input, obj map[string]*string, lg *log.Entry) ([]*AttributeMatch, error) {
 	lg.WithField("obj", fmt.Sprintf("%#v", obj)).Trace("starting match evaluation inside a single object")
-	for k, v := range obj {
+	paths := make([]string, 0, len(obj))
+	for k := range obj {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+
+	var matches []*AttributeMatch
+	for _, k := range paths {
+		v := obj[k]
+		if input.Attribute != "" && !IsAttributePath(k, input.Attribute) {
+			continue
+		}
 		lgo := lg.WithFields(
 			log.Fields{
 				"search_level": input.Match,
@@ -158,13 +239,13 @@ func (s *DefaultSearcher[CC, Matcher]) SearchSingleObject(input *Input, obj map[
 				"value":        aws.StringValue(v),
 			})
 
-		match, err := s.Comparator.IsMatch(k, input.Value)
+		match, err := s.Comparator.IsMatch(input.Value, k)
 		lgo.WithError(err).WithField("is_key_match", match).Trace("key match evaluation")
 		if err != nil {
-			return false, err
+			return nil, err
 		}
 		if match {
-			return true, nil
+			matches = append(matches, s.newAttributeMatch(input.Value, k, k, true))
 		}
 		if v == nil || input.Match != ObjectMatch {
 			lgo.Trace("skipping object search due to conditions")
@@ -174,30 +255,52 @@ func (s *DefaultSearcher[CC, Matcher]) SearchSingleObject(input *Input, obj map[
 		match, err = s.Comparator.IsMatch(input.Value, aws.StringValue(v))
 		lgo.WithError(err).WithField("is_value_match", match).Trace("value match evaluation")
 		if err != nil {
-			return false, err
+			return nil, err
 		}
 		if match {
-			return true, nil
+			matches = append(matches, s.newAttributeMatch(input.Value, k, aws.StringValue(v), false))
 		}
 	}
-	lg.Debug("no matches in single object at all")
-	return false, nil
+	if len(matches) == 0 {
+		lg.Debug("no matches in single object at all")
+	}
+	return matches, nil
 }
 
-func (s *DefaultSearcher[CC, Matcher]) SearchTableData(name string, input *Input, parser ObjParser, lg *log.Entry) ([]*OutputHit, error) {
+// newAttributeMatch offsets are only known if the comparator can find them
+func (s *DefaultSearcher[CC, Matcher]) newAttributeMatch(needle, path, haystack string, isKeyMatch bool) *AttributeMatch {
+	m := &AttributeMatch{Path: path, IsKeyMatch: isKeyMatch}
+	if om, ok := any(s.Comparator).(common.OffsetsMatcher); ok {
+		m.Offsets, _ = om.FindMatchOffsets(needle, haystack)
+	}
+	return m
+}
+
+func (s *DefaultSearcher[CC, Matcher]) SearchTableData(name string, input *Input, parser ObjParser, budget *awsu.DDBCapacityBudget, lg *log.Entry) ([]*OutputHit, error) {
 	var searchables []*OutputHit
 	var parsedErr error
-	err := s.Client.ScanTable(name, func(items []map[string]*dynamodb.AttributeValue) bool {
+	// with multiple segments pages are handled concurrently
+	var mu sync.Mutex
+	checkpoint := input.Checkpoint
+	if checkpoint != nil && checkpoint.IsTableDone(name) {
+		lg.Debug("skipping table already scanned in checkpoint")
+		return nil, nil
+	}
+	scanOpts := &awsu.DDBScanOptions{Segments: input.Segments, Filter: input.Filter, Budget: budget}
+	if checkpoint != nil {
+		checkpoint.ApplyScanOptions(name, scanOpts)
+	}
+	err := s.Client.ScanTableWithOptions(name, scanOpts, func(items []map[string]*dynamodb.AttributeValue) bool {
 		lg.WithField("items", len(items)).Debug("scaning table page items")
 		for _, item := range items {
-			parsedItem, parsedErr := parser.ParseToStrings(item)
+			parsed, parsedErr := parser.Parse(item)
 			if parsedErr != nil {
 				lg.WithField("fail_fast", input.FailFast).WithError(parsedErr).Warningf("error parsing object to string %#v", item)
 				if input.FailFast {
 					return false
 				}
 			}
-			match, err := s.SearchSingleObject(input, parsedItem, lg)
+			matches, err := s.SearchSingleObject(input, parsed.Values, lg)
 			if err != nil {
 				lg.WithField("fail_fast", input.FailFast).WithError(parsedErr).Warningf("error searching matches in object %#v", item)
 				if input.FailFast {
@@ -205,13 +308,19 @@ func (s *DefaultSearcher[CC, Matcher]) SearchTableData(name string, input *Input
 				}
 			}
 
-			if match {
-				hit := &OutputHit{
-					TableName:  name,
-					HitLevel:   input.Match,
-					ObjectData: parsedItem,
+			if len(matches) > 0 {
+				hit := newOutputHit(name, input.Match, parsed, matches)
+				mu.Lock()
+				// another segment may have matched concurrently
+				if input.StopFirstMatch && len(searchables) > 0 {
+					mu.Unlock()
+					return false
 				}
 				searchables = append(searchables, hit)
+				mu.Unlock()
+				if checkpoint != nil {
+					checkpoint.AddHit(hit)
+				}
 				if input.StopFirstMatch {
 					return false
 				}
@@ -9,6 +9,12 @@ type Matcher interface {
 	IsMatch(needle, haystack string) (bool, error)
 }
 
+// OffsetsMatcher matchers that can tell where in the haystack the needle matched
+type OffsetsMatcher interface {
+	// FindMatchOffsets [start, end) byte offsets of every match in the original haystack
+	FindMatchOffsets(needle, haystack string) ([][]int, error)
+}
+
 type RegexMatcher struct {
 	// lower capital letters of path some/Secret/Val -> some/secret/val
 	LowerHaystack bool
@@ -35,3 +41,19 @@ func (m *RegexMatcher) IsMatch(needle, haystack string) (bool, error) {
 
 	return matched, err
 }
+
+func (m *RegexMatcher) FindMatchOffsets(needle, haystack string) ([][]int, error) {
+	// case insensitive matching on the original haystack keeps offsets valid for non ascii text
+	if m.LowerHaystack && m.LowerNeedle {
+		needle = "(?i)" + needle
+	} else if m.LowerHaystack {
+		haystack = strings.ToLower(haystack)
+	} else if m.LowerNeedle {
+		needle = strings.ToLower(needle)
+	}
+	re, err := regexp.Compile(needle)
+	if err != nil {
+		return nil, err
+	}
+	return re.FindAllStringIndex(haystack, -1), nil
+}
@@ -0,0 +1,95 @@
+package s3search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsu "github.com/isan-rivkin/surf/lib/awsu"
+	workPool "github.com/isan-rivkin/surf/lib/common"
+	log "github.com/sirupsen/logrus"
+)
+
+type BucketConfigMatch struct {
+	Config awsu.BucketConfigType
+	// the json document of the configuration or awsu.BucketConfigNotConfigured
+	Document string
+}
+
+func (i *Input) WithBucketConfigs(configs []awsu.BucketConfigType) *Input {
+	i.BucketConfigs = configs
+	return i
+}
+
+// SearchBuckets matches the value against the configuration documents of every bucket matching the bucket pattern
+func (s *DefaultSearcher[CC, Matcher]) SearchBuckets(i *Input) (*Output, error) {
+	out := &Output{
+		BucketToConfigMatches: map[string][]*BucketConfigMatch{},
+		BucketToRegion:        map[string]string{},
+	}
+	targetBuckets, err := s.selectBuckets(i)
+	if err != nil {
+		return nil, err
+	}
+	configs := i.BucketConfigs
+	if len(configs) == 0 {
+		configs = awsu.AllBucketConfigTypes
+	}
+	log.WithField("buckets_number", len(targetBuckets)).Info("searching buckets configuration")
+	if len(targetBuckets) == 0 {
+		return out, nil
+	}
+
+	var mu sync.Mutex
+	workersNum := math.Min(float64(len(targetBuckets)), float64(i.Parallel))
+	pool := workPool.NewWorkerPool(int(workersNum))
+	for _, b := range targetBuckets {
+		bucketName := aws.StringValue(b.Name)
+		pool.Submit(func() {
+			region, err := s.Client.GetBucketRegion(bucketName)
+			if err != nil {
+				log.WithError(err).WithField("bucket", bucketName).Debug("failed detecting bucket region")
+			}
+			matches, err := s.searchBucketConfigs(bucketName, configs, i.Value)
+			if err != nil {
+				log.WithError(err).WithField("bucket", bucketName).Error("failed searching bucket configuration")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if region != "" {
+				out.BucketToRegion[bucketName] = region
+			}
+			if len(matches) > 0 {
+				out.BucketToConfigMatches[bucketName] = matches
+			}
+		})
+	}
+	pool.RunAll()
+	return out, nil
+}
+
+// searchBucketConfigs keeps going on access denied for a single configuration and returns the last error
+func (s *DefaultSearcher[CC, Matcher]) searchBucketConfigs(bucket string, configs []awsu.BucketConfigType, value string) ([]*BucketConfigMatch, error) {
+	var matches []*BucketConfigMatch
+	var lastErr error
+	for _, c := range configs {
+		doc, err := s.Client.GetBucketConfig(bucket, c)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		match, err := s.Comparator.IsMatch(value, doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed matching bucket configuration %s", err.Error())
+		}
+		if match {
+			matches = append(matches, &BucketConfigMatch{Config: c, Document: doc})
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].Config < matches[b].Config
+	})
+	return matches, lastErr
+}
@@ -0,0 +1,43 @@
+package s3search_test
+
+import (
+	"testing"
+
+	"github.com/isan-rivkin/surf/lib/awsu"
+	common "github.com/isan-rivkin/surf/lib/search"
+	search "github.com/isan-rivkin/surf/lib/search/s3search"
+	"github.com/magiconair/properties/assert"
+)
+
+type fakeBucketConfigS3 struct {
+	fakeS3
+	configs map[awsu.BucketConfigType]string
+}
+
+func (f *fakeBucketConfigS3) GetBucketConfig(bucket string, configType awsu.BucketConfigType) (string, error) {
+	if doc, exist := f.configs[configType]; exist {
+		return doc, nil
+	}
+	return awsu.BucketConfigNotConfigured, nil
+}
+
+func TestSearchBuckets(t *testing.T) {
+	client := &fakeBucketConfigS3{configs: map[awsu.BucketConfigType]string{
+		awsu.BucketPolicyConfig: `{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:root"}}]}`,
+		awsu.BucketTagsConfig:   `[{"Key":"team","Value":"payments"}]`,
+	}}
+	s := search.NewSearcher[awsu.S3API, common.Matcher](client, common.NewDefaultRegexMatcher())
+
+	out, err := s.SearchBuckets(search.NewSearchInput("bucket", "", "123456789012", 5, false))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(out.BucketToConfigMatches["bucket"]), 1)
+	assert.Equal(t, out.BucketToConfigMatches["bucket"][0].Config, awsu.BucketPolicyConfig)
+
+	// missing configurations are matched as not-configured
+	in := search.NewSearchInput("bucket", "", "^"+awsu.BucketConfigNotConfigured+"$", 5, false).
+		WithBucketConfigs([]awsu.BucketConfigType{awsu.BucketEncryptionConfig, awsu.BucketTagsConfig})
+	out, err = s.SearchBuckets(in)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(out.BucketToConfigMatches["bucket"]), 1)
+	assert.Equal(t, out.BucketToConfigMatches["bucket"][0].Config, awsu.BucketEncryptionConfig)
+}
@@ -0,0 +1,229 @@
+package s3search
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	common "github.com/isan-rivkin/surf/lib/search"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// max length of a line snippet in the output
+	maxSnippetLength = 200
+	// longer lines are matched in chunks of this size
+	maxLineLength = 1024 * 1024
+	// chunks of a long line overlap so a match across the chunk boundary is not missed
+	lineChunkOverlap = 4 * 1024
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+type ContentMatch struct {
+	Key     string
+	Line    int
+	Snippet string
+}
+
+// ContentFilter decides which objects are downloaded for content search
+type ContentFilter struct {
+	// objects larger than this (compressed size) are skipped and only this much decompressed content is read, 0 means no limit
+	MaxObjectSize int64
+	// if set only objects with these extensions are read i.e .json .yaml
+	Extensions []string
+	// if set only objects with a content type containing one of these is read i.e json, text/
+	ContentTypes []string
+}
+
+func (f *ContentFilter) IsObjectAllowed(o types.Object) bool {
+	if f.MaxObjectSize > 0 && o.Size > f.MaxObjectSize {
+		return false
+	}
+	if len(f.Extensions) == 0 {
+		return true
+	}
+	key := aws.StringValue(o.Key)
+	// compressed objects are matched by their inner extension i.e config.json.gz
+	for _, compressedExt := range []string{".gz", ".zst"} {
+		key = strings.TrimSuffix(key, compressedExt)
+	}
+	ext := strings.ToLower(path.Ext(key))
+	for _, allowed := range f.Extensions {
+		if ext == "."+strings.TrimPrefix(strings.ToLower(allowed), ".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ContentFilter) IsContentTypeAllowed(contentType string) bool {
+	if len(f.ContentTypes) == 0 {
+		return true
+	}
+	contentType = strings.ToLower(contentType)
+	for _, allowed := range f.ContentTypes {
+		if strings.Contains(contentType, strings.ToLower(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDecompressedReader sniffs gzip / zstd magic bytes and transparently decompresses, otherwise returns the raw content
+func NewDecompressedReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed creating gzip reader %s", err.Error())
+		}
+		return gz, nil
+	case bytes.HasPrefix(header, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed creating zstd reader %s", err.Error())
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return br, nil
+	}
+}
+
+// isBinary uses the same heuristic as git, a NUL byte in the first chunk
+func isBinary(chunk []byte) bool {
+	return bytes.IndexByte(chunk, 0) != -1
+}
+
+// sizeLimitedReader stops after limit bytes and remembers if there was more content
+type sizeLimitedReader struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// content of exactly limit bytes is not exceeding
+		var probe [1]byte
+		if n, _ := io.ReadFull(l.r, probe[:]); n > 0 {
+			l.exceeded = true
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// SearchContent streams the reader line by line through the matcher, binary content is skipped.
+// maxSize limits the decompressed content (0 means no limit), matches found before the limit are returned with an error.
+func SearchContent(r io.Reader, key, value string, m common.Matcher, maxSize int64) ([]*ContentMatch, error) {
+	decompressed, err := NewDecompressedReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := decompressed.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	var limited *sizeLimitedReader
+	if maxSize > 0 {
+		limited = &sizeLimitedReader{r: decompressed, remaining: maxSize}
+		decompressed = limited
+	}
+
+	br := bufio.NewReaderSize(decompressed, 64*1024)
+	if head, _ := br.Peek(512); isBinary(head) {
+		return nil, nil
+	}
+
+	var matches []*ContentMatch
+	var chunk []byte
+	lineNum := 0
+	// only the first match of a line is reported, the rest of the line is skipped
+	lineMatched := false
+	newLine := true
+	for {
+		fragment, isPrefix, err := br.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return matches, fmt.Errorf("failed reading content of %s at line %d %s", key, lineNum, err.Error())
+		}
+		if newLine {
+			lineNum++
+			lineMatched = false
+			chunk = chunk[:0]
+		}
+		newLine = !isPrefix
+		if lineMatched {
+			continue
+		}
+		chunk = append(chunk, fragment...)
+		// long lines (i.e minified json) are matched in chunks overlapping by lineChunkOverlap
+		if isPrefix && len(chunk) < maxLineLength {
+			continue
+		}
+		match, snippet, err := matchLine(m, value, chunk)
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			lineMatched = true
+			matches = append(matches, &ContentMatch{
+				Key:     key,
+				Line:    lineNum,
+				Snippet: snippet,
+			})
+		} else if isPrefix {
+			chunk = append(chunk[:0], chunk[len(chunk)-lineChunkOverlap:]...)
+		}
+	}
+	if limited != nil && limited.exceeded {
+		return matches, fmt.Errorf("content of %s is larger than %d bytes after decompression, only the beginning was searched", key, maxSize)
+	}
+	return matches, nil
+}
+
+// matchLine the snippet is around the match if the matcher knows where it matched
+func matchLine(m common.Matcher, value string, line []byte) (bool, string, error) {
+	text := string(line)
+	match, err := m.IsMatch(value, text)
+	if err != nil || !match {
+		return false, "", err
+	}
+	if om, ok := m.(common.OffsetsMatcher); ok && len(text) > maxSnippetLength {
+		if offsets, err := om.FindMatchOffsets(value, text); err == nil && len(offsets) > 0 {
+			start := offsets[0][0] - maxSnippetLength/4
+			if start > 0 {
+				return true, "..." + toSnippet(text[start:]), nil
+			}
+		}
+	}
+	return true, toSnippet(text), nil
+}
+
+func toSnippet(line string) string {
+	line = strings.TrimSpace(line)
+	if len(line) > maxSnippetLength {
+		return line[:maxSnippetLength] + "..."
+	}
+	return line
+}
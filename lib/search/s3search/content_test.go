@@ -0,0 +1,94 @@
+package s3search_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	common "github.com/isan-rivkin/surf/lib/search"
+	search "github.com/isan-rivkin/surf/lib/search/s3search"
+	"github.com/klauspost/compress/zstd"
+	"github.com/magiconair/properties/assert"
+)
+
+const testContent = `service: api
+endpoint: https://api.internal.example.com
+replicas: 3
+fallback: https://API.internal.example.com/v2
+`
+
+func gzipped(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestSearchContent(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Payload []byte
+	}{
+		{"plain", []byte(testContent)},
+		{"gzip", gzipped(t, testContent)},
+		{"zstd", zstded(t, testContent)},
+	}
+
+	for _, c := range cases {
+		matches, err := search.SearchContent(bytes.NewReader(c.Payload), "conf.yaml", `api\.internal`, common.NewDefaultRegexMatcher(), 0)
+		assert.Equal(t, err, nil, c.Name)
+		assert.Equal(t, len(matches), 2, c.Name)
+		assert.Equal(t, matches[0].Line, 2, c.Name)
+		assert.Equal(t, matches[1].Line, 4, c.Name)
+		assert.Equal(t, matches[0].Snippet, "endpoint: https://api.internal.example.com", c.Name)
+	}
+}
+
+func TestSearchContentSkipsBinary(t *testing.T) {
+	payload := append([]byte("api.internal"), 0x00, 0x01, 0x02)
+	matches, err := search.SearchContent(bytes.NewReader(payload), "bin", `api`, common.NewDefaultRegexMatcher(), 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 0)
+}
+
+func TestSearchContentLongLines(t *testing.T) {
+	// minified json larger than a single chunk, the match crosses the first chunk boundary
+	long := `{"a":"` + strings.Repeat("x", 1024*1024-5) + `api.internal","b":"` + strings.Repeat("y", 3*1024*1024) + `"}`
+	payload := long + "\nsecond line api.internal\n"
+
+	matches, err := search.SearchContent(strings.NewReader(payload), "min.json", `api\.internal`, common.NewDefaultRegexMatcher(), 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 2, "long line must not stop the search")
+	assert.Equal(t, matches[0].Line, 1)
+	assert.Equal(t, strings.Contains(matches[0].Snippet, "api.internal"), true, "snippet is around the match")
+	assert.Equal(t, matches[1].Line, 2)
+}
+
+func TestSearchContentMaxSize(t *testing.T) {
+	payload := gzipped(t, "api.internal\n"+strings.Repeat("padding\n", 1000)+"api.internal\n")
+
+	matches, err := search.SearchContent(bytes.NewReader(payload), "big.gz", `api\.internal`, common.NewDefaultRegexMatcher(), 1024)
+	assert.Equal(t, err != nil, true, "decompressed content over the limit")
+	assert.Equal(t, len(matches), 1, "matches before the limit are kept")
+
+	matches, err = search.SearchContent(bytes.NewReader(payload), "big.gz", `api\.internal`, common.NewDefaultRegexMatcher(), 1024*1024)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 2)
+}
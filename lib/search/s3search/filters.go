@@ -0,0 +1,69 @@
+package s3search
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+type ObjectMatch struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	StorageClass string
+}
+
+func NewObjectMatch(o types.Object) *ObjectMatch {
+	return &ObjectMatch{
+		Key:          aws.StringValue(o.Key),
+		Size:         o.Size,
+		LastModified: aws.TimeValue(o.LastModified),
+		StorageClass: string(o.StorageClass),
+	}
+}
+
+// ObjectFilter filters listed objects by size, age and storage class before matching, zero values are ignored
+type ObjectFilter struct {
+	MinSize int64
+	MaxSize int64
+	// only objects modified in the last duration i.e 7 days
+	ModifiedWithin time.Duration
+	// only objects last modified before the duration i.e 1 year ago
+	OlderThan      time.Duration
+	StorageClasses []string
+}
+
+func (f *ObjectFilter) IsObjectAllowed(o types.Object) bool {
+	if f == nil {
+		return true
+	}
+	if f.MinSize > 0 && o.Size < f.MinSize {
+		return false
+	}
+	if f.MaxSize > 0 && o.Size > f.MaxSize {
+		return false
+	}
+	age := time.Since(aws.TimeValue(o.LastModified))
+	if f.ModifiedWithin > 0 && age > f.ModifiedWithin {
+		return false
+	}
+	if f.OlderThan > 0 && age < f.OlderThan {
+		return false
+	}
+	if len(f.StorageClasses) > 0 {
+		// objects listed without a storage class are STANDARD
+		class := string(o.StorageClass)
+		if class == "" {
+			class = string(types.ObjectStorageClassStandard)
+		}
+		for _, allowed := range f.StorageClasses {
+			if strings.EqualFold(allowed, class) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package s3search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	search "github.com/isan-rivkin/surf/lib/search/s3search"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestObjectFilter(t *testing.T) {
+	old := types.Object{Key: aws.String("old"), Size: 100, LastModified: aws.Time(time.Now().Add(-400 * 24 * time.Hour)), StorageClass: types.ObjectStorageClassGlacier}
+	recent := types.Object{Key: aws.String("recent"), Size: 5000, LastModified: aws.Time(time.Now().Add(-time.Hour))}
+
+	var noFilter *search.ObjectFilter
+	assert.Equal(t, noFilter.IsObjectAllowed(old), true)
+
+	cases := []struct {
+		Name   string
+		Filter *search.ObjectFilter
+		Old    bool
+		Recent bool
+	}{
+		{"min size", &search.ObjectFilter{MinSize: 1000}, false, true},
+		{"max size", &search.ObjectFilter{MaxSize: 1000}, true, false},
+		{"modified within", &search.ObjectFilter{ModifiedWithin: 7 * 24 * time.Hour}, false, true},
+		{"older than", &search.ObjectFilter{OlderThan: 365 * 24 * time.Hour}, true, false},
+		{"storage class", &search.ObjectFilter{StorageClasses: []string{"glacier"}}, true, false},
+		{"standard by default", &search.ObjectFilter{StorageClasses: []string{"STANDARD"}}, false, true},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.Filter.IsObjectAllowed(old), c.Old, c.Name)
+		assert.Equal(t, c.Filter.IsObjectAllowed(recent), c.Recent, c.Name)
+	}
+}
@@ -0,0 +1,373 @@
+package s3search
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	awsu "github.com/isan-rivkin/surf/lib/awsu"
+	workPool "github.com/isan-rivkin/surf/lib/common"
+	common "github.com/isan-rivkin/surf/lib/search"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	InventoryKeyColumn = "Key"
+	inventoryManifest  = "manifest.json"
+	s3URLPrefix        = "s3://"
+	s3ARNPrefix        = "arn:aws:s3:::"
+	// the inventory output formats that can be searched
+	InventoryFormatCSV     = "CSV"
+	InventoryFormatORC     = "ORC"
+	InventoryFormatParquet = "Parquet"
+)
+
+// ErrUnsupportedInventoryFormat the manifest declares an output format other than CSV, ORC or Parquet
+var ErrUnsupportedInventoryFormat = errors.New("unsupported inventory format")
+
+// InventoryManifest is the manifest.json written by S3 Inventory next to every report
+type InventoryManifest struct {
+	SourceBucket      string          `json:"sourceBucket"`
+	DestinationBucket string          `json:"destinationBucket"`
+	FileFormat        string          `json:"fileFormat"`
+	FileSchema        string          `json:"fileSchema"`
+	Files             []InventoryFile `json:"files"`
+}
+
+type InventoryFile struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+}
+
+func ParseInventoryManifest(r io.Reader) (*InventoryManifest, error) {
+	m := &InventoryManifest{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("failed parsing inventory manifest %s", err.Error())
+	}
+	switch {
+	case strings.EqualFold(m.FileFormat, InventoryFormatCSV):
+		m.FileFormat = InventoryFormatCSV
+	case strings.EqualFold(m.FileFormat, InventoryFormatORC):
+		m.FileFormat = InventoryFormatORC
+	case strings.EqualFold(m.FileFormat, InventoryFormatParquet):
+		m.FileFormat = InventoryFormatParquet
+	default:
+		return nil, fmt.Errorf("%w %s, the supported formats are CSV, ORC and Parquet", ErrUnsupportedInventoryFormat, m.FileFormat)
+	}
+	return m, nil
+}
+
+// Columns returns the csv columns i.e Bucket, Key, Size, LastModifiedDate, StorageClass, EncryptionStatus
+// ORC and Parquet reports carry their own schema, their columns are read from the data files
+func (m *InventoryManifest) Columns() []string {
+	var cols []string
+	for _, c := range strings.Split(m.FileSchema, ",") {
+		cols = append(cols, strings.TrimSpace(c))
+	}
+	return cols
+}
+
+// DestinationBucketName strips the arn from the destination bucket
+func (m *InventoryManifest) DestinationBucketName() string {
+	return strings.TrimPrefix(m.DestinationBucket, s3ARNPrefix)
+}
+
+type InventoryMatch struct {
+	Bucket string
+	Key    string
+	// the column that matched the query
+	Column string
+	// all the columns of the inventory row
+	Fields map[string]string
+}
+
+// SearchInventoryFile streams a (gzipped) csv inventory data file and matches the query against the key and the given columns
+func SearchInventoryFile(r io.Reader, columns, matchColumns []string, value string, m common.Matcher) ([]*InventoryMatch, error) {
+	decompressed, err := NewDecompressedReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := decompressed.(io.Closer); ok {
+		defer c.Close()
+	}
+	if len(matchColumns) == 0 {
+		matchColumns = []string{InventoryKeyColumn}
+	}
+
+	reader := csv.NewReader(decompressed)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var matches []*InventoryMatch
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return matches, fmt.Errorf("failed reading inventory csv %s", err.Error())
+		}
+		fields := map[string]string{}
+		for idx, col := range columns {
+			if idx < len(record) {
+				fields[col] = record[idx]
+			}
+		}
+		// keys are url encoded in csv inventories
+		if key, err := url.QueryUnescape(fields[InventoryKeyColumn]); err == nil {
+			fields[InventoryKeyColumn] = key
+		}
+		match, err := matchInventoryRow(fields, matchColumns, value, m)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil {
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
+
+// SearchColumnarInventoryFile reads an ORC or Parquet inventory data file and matches the query against the key and the given columns.
+// the snake case columns of the file (storage_class) are renamed to the csv column names (StorageClass)
+func SearchColumnarInventoryFile(r io.ReaderAt, size int64, format string, matchColumns []string, value string, m common.Matcher) ([]*InventoryMatch, error) {
+	if len(matchColumns) == 0 {
+		matchColumns = []string{InventoryKeyColumn}
+	}
+	read := readParquetRows
+	if strings.EqualFold(format, InventoryFormatORC) {
+		read = readOrcRows
+	}
+	var matches []*InventoryMatch
+	err := read(r, size, func(row map[string]string) error {
+		fields := make(map[string]string, len(row))
+		for col, val := range row {
+			fields[inventoryColumnName(col)] = val
+		}
+		match, err := matchInventoryRow(fields, matchColumns, value, m)
+		if err != nil {
+			return err
+		}
+		if match != nil {
+			matches = append(matches, match)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// matchInventoryRow returns the match of the first matching column or nil
+func matchInventoryRow(fields map[string]string, matchColumns []string, value string, m common.Matcher) (*InventoryMatch, error) {
+	for _, col := range matchColumns {
+		val, exist := fields[col]
+		if !exist {
+			continue
+		}
+		match, err := m.IsMatch(value, val)
+		if err != nil {
+			return nil, fmt.Errorf("failed matching inventory column %s %s", col, err.Error())
+		}
+		if match {
+			return &InventoryMatch{
+				Bucket: fields["Bucket"],
+				Key:    fields[InventoryKeyColumn],
+				Column: col,
+				Fields: fields,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// inventoryColumnName converts the ORC / Parquet field names to the csv names i.e last_modified_date to LastModifiedDate and e_tag to ETag
+func inventoryColumnName(field string) string {
+	parts := strings.Split(field, "_")
+	for idx, p := range parts {
+		if p != "" {
+			parts[idx] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+type InventoryInput struct {
+	// manifest.json location, s3://bucket/path/manifest.json or a local file or directory containing manifest.json
+	Manifest string
+	Value    string
+	// inventory columns to match besides the key i.e StorageClass, EncryptionStatus, Size
+	Columns  []string
+	Parallel int
+}
+
+type InventoryOutput struct {
+	Manifest *InventoryManifest
+	Matches  []*InventoryMatch
+}
+
+type InventorySearcher struct {
+	Client     awsu.S3API
+	Comparator common.Matcher
+}
+
+func NewInventorySearcher(c awsu.S3API, m common.Matcher) *InventorySearcher {
+	return &InventorySearcher{
+		Client:     c,
+		Comparator: m,
+	}
+}
+
+// inventoryOpener opens the manifest data files either from the destination bucket or a local copy
+type inventoryOpener func(key string) (io.ReadCloser, error)
+
+func (s *InventorySearcher) Search(i *InventoryInput) (*InventoryOutput, error) {
+	manifestReader, opener, err := s.resolveManifest(i.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := ParseInventoryManifest(manifestReader)
+	manifestReader.Close()
+	if err != nil {
+		return nil, err
+	}
+	if opener == nil {
+		opener = s.newS3Opener(manifest.DestinationBucketName())
+	}
+
+	columns := manifest.Columns()
+	matchColumns := append([]string{InventoryKeyColumn}, i.Columns...)
+
+	log.WithFields(log.Fields{
+		"source_bucket": manifest.SourceBucket,
+		"files":         len(manifest.Files),
+	}).Info("searching inventory")
+
+	out := &InventoryOutput{Manifest: manifest}
+	if len(manifest.Files) == 0 {
+		return out, nil
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	workersNum := math.Min(float64(len(manifest.Files)), float64(i.Parallel))
+	pool := workPool.NewWorkerPool(int(workersNum))
+	for _, f := range manifest.Files {
+		key := f.Key
+		pool.Submit(func() {
+			r, err := opener(key)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				return
+			}
+			defer r.Close()
+			matches, err := searchInventoryDataFile(r, manifest.FileFormat, columns, matchColumns, i.Value, s.Comparator)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("inventory file %s %s", key, err.Error()))
+			}
+			out.Matches = append(out.Matches, matches...)
+		})
+	}
+	pool.RunAll()
+
+	for _, err := range errs {
+		log.WithError(err).Error("failed searching inventory file")
+	}
+	if len(errs) == len(manifest.Files) {
+		return nil, fmt.Errorf("failed searching all inventory files %s", errs[0].Error())
+	}
+
+	sort.SliceStable(out.Matches, func(a, b int) bool {
+		return out.Matches[a].Key < out.Matches[b].Key
+	})
+	return out, nil
+}
+
+// searchInventoryDataFile streams csv files, ORC and Parquet files need random access so remote files are first spooled to a temp file
+func searchInventoryDataFile(r io.Reader, format string, columns, matchColumns []string, value string, m common.Matcher) ([]*InventoryMatch, error) {
+	if format == InventoryFormatCSV {
+		return SearchInventoryFile(r, columns, matchColumns, value, m)
+	}
+	f, isFile := r.(*os.File)
+	if !isFile {
+		tmp, err := os.CreateTemp("", "surf-inventory-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed creating inventory temp file %s", err.Error())
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if _, err := io.Copy(tmp, r); err != nil {
+			return nil, fmt.Errorf("failed downloading inventory file %s", err.Error())
+		}
+		f = tmp
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading inventory file info %s", err.Error())
+	}
+	return SearchColumnarInventoryFile(f, info.Size(), format, matchColumns, value, m)
+}
+
+// resolveManifest returns the manifest content and, for local manifests, an opener of local data files
+func (s *InventorySearcher) resolveManifest(location string) (io.ReadCloser, inventoryOpener, error) {
+	if strings.HasPrefix(location, s3URLPrefix) {
+		bucket, key, _ := strings.Cut(strings.TrimPrefix(location, s3URLPrefix), "/")
+		if key == "" || strings.HasSuffix(key, "/") {
+			key += inventoryManifest
+		}
+		obj, err := s.Client.GetObject(bucket, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		return obj.Body, nil, nil
+	}
+
+	path := location
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, inventoryManifest)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed opening inventory manifest %s", err.Error())
+	}
+	return f, newLocalOpener(filepath.Dir(path)), nil
+}
+
+func (s *InventorySearcher) newS3Opener(bucket string) inventoryOpener {
+	return func(key string) (io.ReadCloser, error) {
+		obj, err := s.Client.GetObject(bucket, key)
+		if err != nil {
+			return nil, err
+		}
+		return obj.Body, nil
+	}
+}
+
+// newLocalOpener resolves data file keys against a local copy of the inventory.
+// the manifest keys are full destination keys (prefix/source-bucket/config-id/data/file.csv.gz),
+// the local copy usually keeps only the tail of the key so every suffix is tried under the manifest dir and its parent
+func newLocalOpener(manifestDir string) inventoryOpener {
+	return func(key string) (io.ReadCloser, error) {
+		parts := strings.Split(key, "/")
+		for _, dir := range []string{manifestDir, filepath.Dir(manifestDir)} {
+			for idx := range parts {
+				candidate := filepath.Join(append([]string{dir}, parts[idx:]...)...)
+				if f, err := os.Open(candidate); err == nil {
+					return f, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("inventory data file %s not found under %s", key, manifestDir)
+	}
+}
@@ -0,0 +1,66 @@
+package s3search_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	common "github.com/isan-rivkin/surf/lib/search"
+	search "github.com/isan-rivkin/surf/lib/search/s3search"
+	"github.com/magiconair/properties/assert"
+)
+
+const testInventoryManifest = `{
+  "sourceBucket": "datalake",
+  "destinationBucket": "arn:aws:s3:::inventories",
+  "fileFormat": "CSV",
+  "fileSchema": "Bucket, Key, Size, LastModifiedDate, StorageClass, EncryptionStatus",
+  "files": [{"key": "inventory/datalake/daily/data/part-1.csv.gz", "size": 100}]
+}`
+
+const testInventoryData = `"datalake","events/2023/a.parquet","1024","2023-01-01T00:00:00.000Z","STANDARD","SSE-S3"
+"datalake","events/2023/b%20c.parquet","2048","2023-01-02T00:00:00.000Z","GLACIER","NOT-SSE"
+"datalake","logs/x.log","10","2023-01-02T00:00:00.000Z","STANDARD","SSE-S3"
+`
+
+func TestLocalInventorySearch(t *testing.T) {
+	// local copy layout: <dir>/<timestamp>/manifest.json and <dir>/data/part-1.csv.gz
+	dir := t.TempDir()
+	manifestDir := filepath.Join(dir, "2023-01-03T00-00Z")
+	assert.Equal(t, os.MkdirAll(manifestDir, 0755), nil)
+	assert.Equal(t, os.MkdirAll(filepath.Join(dir, "data"), 0755), nil)
+	assert.Equal(t, os.WriteFile(filepath.Join(manifestDir, "manifest.json"), []byte(testInventoryManifest), 0644), nil)
+	assert.Equal(t, os.WriteFile(filepath.Join(dir, "data", "part-1.csv.gz"), gzipped(t, testInventoryData), 0644), nil)
+
+	s := search.NewInventorySearcher(nil, common.NewDefaultRegexMatcher())
+
+	out, err := s.Search(&search.InventoryInput{Manifest: manifestDir, Value: `\.parquet$`, Parallel: 2})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(out.Matches), 2)
+	assert.Equal(t, out.Matches[1].Key, "events/2023/b c.parquet")
+	assert.Equal(t, out.Matches[1].Fields["StorageClass"], "GLACIER")
+
+	out, err = s.Search(&search.InventoryInput{Manifest: manifestDir, Value: `^GLACIER$`, Columns: []string{"StorageClass"}, Parallel: 2})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(out.Matches), 1)
+	assert.Equal(t, out.Matches[0].Column, "StorageClass")
+}
+
+func TestInventoryManifestFormats(t *testing.T) {
+	m, err := search.ParseInventoryManifest(strings.NewReader(testInventoryManifest))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, m.Columns()[1], search.InventoryKeyColumn)
+
+	for format, expected := range map[string]string{"ORC": search.InventoryFormatORC, "parquet": search.InventoryFormatParquet, "csv": search.InventoryFormatCSV} {
+		manifest := strings.Replace(testInventoryManifest, `"CSV"`, `"`+format+`"`, 1)
+		m, err := search.ParseInventoryManifest(strings.NewReader(manifest))
+		assert.Equal(t, err, nil, format)
+		assert.Equal(t, m.FileFormat, expected)
+	}
+
+	manifest := strings.Replace(testInventoryManifest, `"CSV"`, `"JSON"`, 1)
+	_, err = search.ParseInventoryManifest(strings.NewReader(manifest))
+	assert.Equal(t, errors.Is(err, search.ErrUnsupportedInventoryFormat), true)
+}
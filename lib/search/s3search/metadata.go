@@ -0,0 +1,152 @@
+package s3search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	workPool "github.com/isan-rivkin/surf/lib/common"
+	log "github.com/sirupsen/logrus"
+)
+
+type MetadataMatch struct {
+	Key string
+	// the attribute that matched i.e meta.owner, tag.team, content-type
+	Attribute string
+	Value     string
+}
+
+type MetadataOptions struct {
+	// fetch object tags, costs an additional request per object
+	WithTags bool
+	// max objects per bucket to inspect, 0 is unlimited
+	Limit int
+}
+
+const (
+	userMetadataPrefix = "meta."
+	tagPrefix          = "tag."
+)
+
+// objectAttributes flattens the interesting head object fields and tags into attribute -> value
+func objectAttributes(head map[string]string, contentType, storageClass string, tags []types.Tag) map[string]string {
+	attrs := map[string]string{}
+	for k, v := range head {
+		attrs[userMetadataPrefix+k] = v
+	}
+	if contentType != "" {
+		attrs["content-type"] = contentType
+	}
+	if storageClass != "" {
+		attrs["storage-class"] = storageClass
+	}
+	for _, t := range tags {
+		attrs[tagPrefix+aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return attrs
+}
+
+func (s *DefaultSearcher[CC, Matcher]) searchObjectsMetadata(bucket string, objects []types.Object, i *Input) ([]*MetadataMatch, error) {
+	opts := i.MetadataOptions
+	if opts == nil {
+		opts = &MetadataOptions{}
+	}
+	// one more than the limit tells if objects were left out
+	limit := opts.Limit
+	if limit > 0 {
+		limit++
+	}
+	candidates, err := s.selectCandidates(objects, i, nil, limit)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit > 0 && len(candidates) > opts.Limit {
+		candidates = candidates[:opts.Limit]
+		log.WithFields(log.Fields{
+			"bucket": bucket,
+			"limit":  opts.Limit,
+		}).Warn("metadata limit reached, remaining objects in bucket were not inspected (use --metadata-limit, --prefix or --key-pattern)")
+	}
+
+	log.WithFields(log.Fields{
+		"bucket":     bucket,
+		"candidates": len(candidates),
+		"total_keys": len(objects),
+	}).Debug("searching objects metadata")
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	var mu sync.Mutex
+	var matches []*MetadataMatch
+	workersNum := math.Min(float64(len(candidates)), float64(i.Parallel))
+	pool := workPool.NewWorkerPool(int(workersNum))
+	for _, o := range candidates {
+		key := aws.StringValue(o.Key)
+		pool.Submit(func() {
+			found, err := s.searchObjectMetadata(bucket, key, i, opts)
+			if err != nil {
+				log.WithError(err).WithField("key", key).Warn("failed searching object metadata")
+				return
+			}
+			mu.Lock()
+			matches = append(matches, found...)
+			mu.Unlock()
+		})
+	}
+	pool.RunAll()
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].Key != matches[b].Key {
+			return matches[a].Key < matches[b].Key
+		}
+		return matches[a].Attribute < matches[b].Attribute
+	})
+	return matches, nil
+}
+
+func (s *DefaultSearcher[CC, Matcher]) searchObjectMetadata(bucket, key string, i *Input, opts *MetadataOptions) ([]*MetadataMatch, error) {
+	head, err := s.Client.HeadObject(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	var tags []types.Tag
+	if opts.WithTags {
+		if tags, err = s.Client.GetObjectTags(bucket, key); err != nil {
+			return nil, err
+		}
+	}
+
+	attrs := objectAttributes(head.Metadata, aws.StringValue(head.ContentType), string(head.StorageClass), tags)
+
+	var matches []*MetadataMatch
+	for attr, val := range attrs {
+		for _, candidate := range searchableMetadata(attr, val) {
+			match, err := s.Comparator.IsMatch(i.Value, candidate)
+			if err != nil {
+				return nil, fmt.Errorf("failed matching metadata %s", err.Error())
+			}
+			if match {
+				matches = append(matches, &MetadataMatch{Key: key, Attribute: attr, Value: val})
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+// searchableMetadata user metadata and tags match by name or value i.e 'owner' or 'team-a',
+// built in attributes match by value only otherwise a query like 'type' matches every object
+func searchableMetadata(attr, val string) []string {
+	for _, prefix := range []string{userMetadataPrefix, tagPrefix} {
+		if name, isUser := strings.CutPrefix(attr, prefix); isUser {
+			return []string{name, val}
+		}
+	}
+	return []string{val}
+}
@@ -0,0 +1,105 @@
+package s3search_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/isan-rivkin/surf/lib/awsu"
+	common "github.com/isan-rivkin/surf/lib/search"
+	search "github.com/isan-rivkin/surf/lib/search/s3search"
+	"github.com/magiconair/properties/assert"
+)
+
+// fakeMetadataS3 serves HEAD and tags of in memory objects
+type fakeMetadataS3 struct {
+	awsu.S3API
+	heads map[string]*s3.HeadObjectOutput
+	tags  map[string][]types.Tag
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeMetadataS3) ListAllBuckets() ([]types.Bucket, error) {
+	return []types.Bucket{{Name: aws.String("bucket")}}, nil
+}
+
+func (f *fakeMetadataS3) GetBucketRegion(bucket string) (string, error) {
+	return "us-east-1", nil
+}
+
+func (f *fakeMetadataS3) ListAllObjects(bucket, prefix string) ([]types.Object, error) {
+	var objects []types.Object
+	for _, k := range []string{"a.zip", "b.zip", "c.txt"} {
+		objects = append(objects, types.Object{Key: aws.String(k)})
+	}
+	return objects, nil
+}
+
+func (f *fakeMetadataS3) HeadObject(bucket, key string) (*s3.HeadObjectOutput, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	return f.heads[key], nil
+}
+
+func (f *fakeMetadataS3) GetObjectTags(bucket, key string) ([]types.Tag, error) {
+	return f.tags[key], nil
+}
+
+func newFakeMetadataS3() *fakeMetadataS3 {
+	return &fakeMetadataS3{
+		heads: map[string]*s3.HeadObjectOutput{
+			"a.zip": {ContentType: aws.String("application/zip"), Metadata: map[string]string{"owner": "team-payments"}},
+			"b.zip": {ContentType: aws.String("application/zip"), StorageClass: types.StorageClassGlacier},
+			"c.txt": {ContentType: aws.String("text/plain"), Metadata: map[string]string{"build": "42"}},
+		},
+		tags: map[string][]types.Tag{
+			"c.txt": {{Key: aws.String("team"), Value: aws.String("payments")}},
+		},
+	}
+}
+
+func metadataMatches(t *testing.T, client *fakeMetadataS3, i *search.Input) []string {
+	s := search.NewSearcher[awsu.S3API, common.Matcher](client, common.NewDefaultRegexMatcher())
+	out, err := s.Search(i)
+	assert.Equal(t, err, nil)
+	var found []string
+	for _, m := range out.BucketToMetadataMatches["bucket"] {
+		found = append(found, m.Key+"|"+m.Attribute+"="+m.Value)
+	}
+	return found
+}
+
+func TestMetadataSearch(t *testing.T) {
+	client := newFakeMetadataS3()
+	found := metadataMatches(t, client, search.NewSearchInput("bucket", "", "payments", 5, false).WithMetadataSearch("", &search.MetadataOptions{}))
+	assert.Equal(t, found, []string{"a.zip|meta.owner=team-payments"}, "tags are not fetched by default")
+
+	found = metadataMatches(t, client, search.NewSearchInput("bucket", "", "payments", 5, false).WithMetadataSearch("", &search.MetadataOptions{WithTags: true}))
+	assert.Equal(t, found, []string{"a.zip|meta.owner=team-payments", "c.txt|tag.team=payments"})
+
+	// user metadata and tag names match too
+	found = metadataMatches(t, client, search.NewSearchInput("bucket", "", "^(owner|team)$", 5, false).WithMetadataSearch("", &search.MetadataOptions{WithTags: true}))
+	assert.Equal(t, found, []string{"a.zip|meta.owner=team-payments", "c.txt|tag.team=payments"})
+
+	// built in attributes match by value only
+	found = metadataMatches(t, client, search.NewSearchInput("bucket", "", "type", 5, false).WithMetadataSearch("", &search.MetadataOptions{}))
+	assert.Equal(t, len(found), 0, "no object has metadata with 'type'")
+	found = metadataMatches(t, client, search.NewSearchInput("bucket", "", "storage|class|meta", 5, false).WithMetadataSearch("", &search.MetadataOptions{}))
+	assert.Equal(t, len(found), 0)
+	found = metadataMatches(t, client, search.NewSearchInput("bucket", "", "glacier", 5, false).WithMetadataSearch("", &search.MetadataOptions{}))
+	assert.Equal(t, found, []string{"b.zip|storage-class=GLACIER"})
+
+	found = metadataMatches(t, client, search.NewSearchInput("bucket", "", "application/zip", 5, false).WithMetadataSearch(`^b`, &search.MetadataOptions{}))
+	assert.Equal(t, found, []string{"b.zip|content-type=application/zip"}, "key pattern limits the objects inspected")
+}
+
+func TestMetadataSearchLimit(t *testing.T) {
+	client := newFakeMetadataS3()
+	found := metadataMatches(t, client, search.NewSearchInput("bucket", "", "zip|text", 5, false).WithMetadataSearch("", &search.MetadataOptions{Limit: 2}))
+	assert.Equal(t, found, []string{"a.zip|content-type=application/zip", "b.zip|content-type=application/zip"})
+	assert.Equal(t, client.calls, 2, "objects past the limit are not inspected")
+}
@@ -0,0 +1,915 @@
+package s3search
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// a minimal orc reader for flat schemas like S3 Inventory reports, see https://orc.apache.org/specification/ORCv1/
+
+var orcMagic = []byte("ORC")
+
+// orc compression kinds
+const (
+	orcNone   = 0
+	orcZlib   = 1
+	orcSnappy = 2
+	orcZstd   = 5
+)
+
+// orc type kinds
+const (
+	orcBoolean          = 0
+	orcByte             = 1
+	orcShort            = 2
+	orcInt              = 3
+	orcLong             = 4
+	orcFloat            = 5
+	orcDouble           = 6
+	orcString           = 7
+	orcBinary           = 8
+	orcTimestamp        = 9
+	orcStruct           = 12
+	orcDate             = 15
+	orcVarchar          = 16
+	orcChar             = 17
+	orcTimestampInstant = 18
+)
+
+// orc stream and column encoding kinds
+const (
+	orcStreamPresent        = 0
+	orcStreamData           = 1
+	orcStreamLength         = 2
+	orcStreamDictionaryData = 3
+	orcStreamSecondary      = 5
+
+	orcDirect       = 0
+	orcDictionary   = 1
+	orcDirectV2     = 2
+	orcDictionaryV2 = 3
+)
+
+// orc timestamps are seconds since 2015-01-01 UTC
+var orcTimestampEpoch = time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+// readOrcRows calls onRow with every row of the root struct, null values are left out of the row
+func readOrcRows(r io.ReaderAt, size int64, onRow func(map[string]string) error) error {
+	if size < int64(len(orcMagic))+1 {
+		return fmt.Errorf("failed reading orc, file too small")
+	}
+	head := make([]byte, len(orcMagic))
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return fmt.Errorf("failed reading orc header %s", err.Error())
+	}
+	if !bytes.Equal(head, orcMagic) {
+		return fmt.Errorf("failed reading orc, not an orc file")
+	}
+	psLen := make([]byte, 1)
+	if _, err := r.ReadAt(psLen, size-1); err != nil {
+		return fmt.Errorf("failed reading orc postscript %s", err.Error())
+	}
+	psStart := size - 1 - int64(psLen[0])
+	if psStart < 0 {
+		return fmt.Errorf("failed reading orc, invalid postscript length")
+	}
+	psRaw := make([]byte, psLen[0])
+	if _, err := r.ReadAt(psRaw, psStart); err != nil {
+		return fmt.Errorf("failed reading orc postscript %s", err.Error())
+	}
+	ps, err := parseOrcMessage(psRaw)
+	if err != nil {
+		return fmt.Errorf("failed parsing orc postscript %s", err.Error())
+	}
+	compression := ps.uint(2)
+
+	footerLen := int64(ps.uint(1))
+	if footerLen > psStart {
+		return fmt.Errorf("failed reading orc, invalid footer length %d", footerLen)
+	}
+	footerRaw := make([]byte, footerLen)
+	if _, err := r.ReadAt(footerRaw, psStart-footerLen); err != nil {
+		return fmt.Errorf("failed reading orc footer %s", err.Error())
+	}
+	footer, err := readOrcCompressedMessage(compression, footerRaw)
+	if err != nil {
+		return fmt.Errorf("failed parsing orc footer %s", err.Error())
+	}
+
+	types, err := footer.messages(4)
+	if err != nil {
+		return fmt.Errorf("failed parsing orc types %s", err.Error())
+	}
+	if len(types) == 0 || types[0].uint(1) != orcStruct {
+		return fmt.Errorf("failed reading orc, the root type is not a struct")
+	}
+	// the direct children of the root struct are the inventory columns
+	columnIDs := types[0].uints(2)
+	names := types[0].all(3)
+	columns := map[uint64]string{}
+	for idx, id := range columnIDs {
+		if idx >= len(names) || int(id) >= len(types) {
+			continue
+		}
+		if name, ok := names[idx].([]byte); ok {
+			columns[id] = string(name)
+		}
+	}
+
+	stripes, err := footer.messages(3)
+	if err != nil {
+		return fmt.Errorf("failed parsing orc stripes %s", err.Error())
+	}
+	for _, stripe := range stripes {
+		if err := readOrcStripe(r, size, compression, stripe, types, columns, onRow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readOrcStripe(r io.ReaderAt, size int64, compression uint64, stripe orcMessage, types []orcMessage, columns map[uint64]string, onRow func(map[string]string) error) error {
+	offset := int64(stripe.uint(1))
+	dataEnd := stripe.uint(2) + stripe.uint(3)
+	total := dataEnd + stripe.uint(4)
+	if offset < 0 || total > uint64(size-offset) {
+		return fmt.Errorf("failed reading orc, invalid stripe range")
+	}
+	raw := make([]byte, total)
+	if _, err := r.ReadAt(raw, offset); err != nil {
+		return fmt.Errorf("failed reading orc stripe %s", err.Error())
+	}
+	stripeFooter, err := readOrcCompressedMessage(compression, raw[dataEnd:])
+	if err != nil {
+		return fmt.Errorf("failed parsing orc stripe footer %s", err.Error())
+	}
+	streamsInfo, err := stripeFooter.messages(1)
+	if err != nil {
+		return fmt.Errorf("failed parsing orc streams %s", err.Error())
+	}
+	encodings, err := stripeFooter.messages(2)
+	if err != nil {
+		return fmt.Errorf("failed parsing orc column encodings %s", err.Error())
+	}
+
+	// streams are laid out one after the other from the stripe start, index streams included
+	streams := map[uint64]map[uint64][]byte{}
+	var pos uint64
+	for _, s := range streamsInfo {
+		length := s.uint(3)
+		if pos+length > dataEnd {
+			return fmt.Errorf("failed reading orc, invalid stream length")
+		}
+		column := s.uint(2)
+		if _, exist := columns[column]; exist {
+			data, err := orcDecompress(compression, raw[pos:pos+length])
+			if err != nil {
+				return fmt.Errorf("failed decompressing orc stream %s", err.Error())
+			}
+			if streams[column] == nil {
+				streams[column] = map[uint64][]byte{}
+			}
+			streams[column][s.uint(1)] = data
+		}
+		pos += length
+	}
+
+	rows := int(stripe.uint(5))
+	values := map[string][]*string{}
+	for id, name := range columns {
+		var encoding orcMessage
+		if int(id) < len(encodings) {
+			encoding = encodings[id]
+		}
+		vals, err := readOrcColumn(types[id].uint(1), encoding, streams[id], rows)
+		if err != nil {
+			return fmt.Errorf("failed reading orc column %s %s", name, err.Error())
+		}
+		if vals != nil {
+			values[name] = vals
+		}
+	}
+	for row := 0; row < rows; row++ {
+		fields := map[string]string{}
+		for name, vals := range values {
+			if row < len(vals) && vals[row] != nil {
+				fields[name] = *vals[row]
+			}
+		}
+		if err := onRow(fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readOrcColumn returns the column values as text, nil for unsupported types which are skipped
+func readOrcColumn(kind uint64, encoding orcMessage, streams map[uint64][]byte, rows int) ([]*string, error) {
+	present := make([]bool, rows)
+	nonNull := rows
+	if data, exist := streams[orcStreamPresent]; exist {
+		var err error
+		if present, err = decodeOrcBooleans(data, rows); err != nil {
+			return nil, err
+		}
+		nonNull = 0
+		for _, p := range present {
+			if p {
+				nonNull++
+			}
+		}
+	} else {
+		for idx := range present {
+			present[idx] = true
+		}
+	}
+	encodingKind := encoding.uint(1)
+	v2 := encodingKind == orcDirectV2 || encodingKind == orcDictionaryV2
+
+	var decoded []string
+	switch kind {
+	case orcBoolean:
+		vals, err := decodeOrcBooleans(streams[orcStreamData], nonNull)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vals {
+			decoded = append(decoded, strconv.FormatBool(v))
+		}
+	case orcByte:
+		vals, err := decodeOrcByteRLE(streams[orcStreamData], nonNull)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vals {
+			decoded = append(decoded, strconv.Itoa(int(int8(v))))
+		}
+	case orcShort, orcInt, orcLong:
+		vals, err := decodeOrcInts(streams[orcStreamData], true, v2, nonNull)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vals {
+			decoded = append(decoded, strconv.FormatInt(v, 10))
+		}
+	case orcFloat, orcDouble:
+		width := 4
+		if kind == orcDouble {
+			width = 8
+		}
+		data := streams[orcStreamData]
+		if len(data) < width*nonNull {
+			return nil, fmt.Errorf("unexpected end of orc stream")
+		}
+		for idx := 0; idx < nonNull; idx++ {
+			if kind == orcFloat {
+				v := math.Float32frombits(binary.LittleEndian.Uint32(data[idx*width:]))
+				decoded = append(decoded, strconv.FormatFloat(float64(v), 'g', -1, 32))
+			} else {
+				v := math.Float64frombits(binary.LittleEndian.Uint64(data[idx*width:]))
+				decoded = append(decoded, strconv.FormatFloat(v, 'g', -1, 64))
+			}
+		}
+	case orcString, orcBinary, orcVarchar, orcChar:
+		var err error
+		if encodingKind == orcDictionary || encodingKind == orcDictionaryV2 {
+			decoded, err = decodeOrcDictionaryStrings(streams, v2, int(encoding.uint(2)), nonNull)
+		} else {
+			decoded, err = decodeOrcDirectStrings(streams, v2, nonNull)
+		}
+		if err != nil {
+			return nil, err
+		}
+	case orcTimestamp, orcTimestampInstant:
+		seconds, err := decodeOrcInts(streams[orcStreamData], true, v2, nonNull)
+		if err != nil {
+			return nil, err
+		}
+		nanos, err := decodeOrcInts(streams[orcStreamSecondary], false, v2, nonNull)
+		if err != nil {
+			return nil, err
+		}
+		for idx := range seconds {
+			decoded = append(decoded, formatInventoryTime(time.Unix(orcTimestampEpoch+seconds[idx], orcNanos(nanos[idx]))))
+		}
+	case orcDate:
+		days, err := decodeOrcInts(streams[orcStreamData], true, v2, nonNull)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range days {
+			decoded = append(decoded, time.Unix(d*24*60*60, 0).UTC().Format("2006-01-02"))
+		}
+	default:
+		return nil, nil
+	}
+
+	values := make([]*string, rows)
+	next := 0
+	for row := 0; row < rows; row++ {
+		if !present[row] || next >= len(decoded) {
+			continue
+		}
+		values[row] = &decoded[next]
+		next++
+	}
+	return values, nil
+}
+
+// orcNanos the low 3 bits are the number of trailing zeros that were dropped minus one
+func orcNanos(encoded int64) int64 {
+	zeros := encoded & 7
+	nanos := encoded >> 3
+	if zeros != 0 {
+		for z := int64(0); z <= zeros; z++ {
+			nanos *= 10
+		}
+	}
+	return nanos
+}
+
+func decodeOrcDirectStrings(streams map[uint64][]byte, v2 bool, count int) ([]string, error) {
+	lengths, err := decodeOrcInts(streams[orcStreamLength], false, v2, count)
+	if err != nil {
+		return nil, err
+	}
+	return splitOrcStrings(streams[orcStreamData], lengths)
+}
+
+func decodeOrcDictionaryStrings(streams map[uint64][]byte, v2 bool, dictSize, count int) ([]string, error) {
+	lengths, err := decodeOrcInts(streams[orcStreamLength], false, v2, dictSize)
+	if err != nil {
+		return nil, err
+	}
+	dict, err := splitOrcStrings(streams[orcStreamDictionaryData], lengths)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := decodeOrcInts(streams[orcStreamData], false, v2, count)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, count)
+	for _, idx := range indexes {
+		if idx < 0 || int(idx) >= len(dict) {
+			return nil, fmt.Errorf("dictionary index %d out of range", idx)
+		}
+		out = append(out, dict[idx])
+	}
+	return out, nil
+}
+
+func splitOrcStrings(data []byte, lengths []int64) ([]string, error) {
+	out := make([]string, 0, len(lengths))
+	pos := int64(0)
+	for _, l := range lengths {
+		if l < 0 || pos+l > int64(len(data)) {
+			return nil, fmt.Errorf("unexpected end of orc string data")
+		}
+		out = append(out, string(data[pos:pos+l]))
+		pos += l
+	}
+	return out, nil
+}
+
+// readOrcCompressedMessage decompresses and parses a footer or a stripe footer
+func readOrcCompressedMessage(compression uint64, raw []byte) (orcMessage, error) {
+	data, err := orcDecompress(compression, raw)
+	if err != nil {
+		return nil, err
+	}
+	return parseOrcMessage(data)
+}
+
+// orcDecompress every compressed chunk starts with a 3 bytes little endian header of length << 1 | isOriginal
+func orcDecompress(compression uint64, data []byte) ([]byte, error) {
+	if compression == orcNone {
+		return data, nil
+	}
+	var out []byte
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return nil, fmt.Errorf("invalid orc compression chunk header")
+		}
+		header := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+		length := header >> 1
+		data = data[3:]
+		if length > len(data) {
+			return nil, fmt.Errorf("invalid orc compression chunk length %d", length)
+		}
+		chunk := data[:length]
+		data = data[length:]
+		if header&1 == 1 {
+			out = append(out, chunk...)
+			continue
+		}
+		switch compression {
+		case orcZlib:
+			fr := flate.NewReader(bytes.NewReader(chunk))
+			decompressed, err := io.ReadAll(fr)
+			fr.Close()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, decompressed...)
+		case orcSnappy:
+			decompressed, err := snappy.Decode(nil, chunk)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, decompressed...)
+		case orcZstd:
+			dec, err := zstd.NewReader(nil)
+			if err != nil {
+				return nil, err
+			}
+			out, err = dec.DecodeAll(chunk, out)
+			dec.Close()
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported orc compression kind %d", compression)
+		}
+	}
+	return out, nil
+}
+
+// decodeOrcByteRLE control >= 0 is a run of control + 3 copies of the next byte, otherwise -control literal bytes
+func decodeOrcByteRLE(data []byte, count int) ([]byte, error) {
+	out := make([]byte, 0, count)
+	pos := 0
+	for len(out) < count {
+		if pos >= len(data) {
+			return nil, fmt.Errorf("unexpected end of orc byte rle")
+		}
+		control := int8(data[pos])
+		pos++
+		if control >= 0 {
+			if pos >= len(data) {
+				return nil, fmt.Errorf("unexpected end of orc byte rle")
+			}
+			for i := 0; i < int(control)+3; i++ {
+				out = append(out, data[pos])
+			}
+			pos++
+			continue
+		}
+		literals := -int(control)
+		if pos+literals > len(data) {
+			return nil, fmt.Errorf("unexpected end of orc byte rle")
+		}
+		out = append(out, data[pos:pos+literals]...)
+		pos += literals
+	}
+	return out[:count], nil
+}
+
+// decodeOrcBooleans byte rle of bit sets, most significant bit first
+func decodeOrcBooleans(data []byte, count int) ([]bool, error) {
+	packed, err := decodeOrcByteRLE(data, (count+7)/8)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]bool, count)
+	for idx := range out {
+		out[idx] = packed[idx/8]>>(7-idx%8)&1 == 1
+	}
+	return out, nil
+}
+
+// decodeOrcInts decodes count integers of the RLE v1 or v2 encodings
+func decodeOrcInts(data []byte, signed, v2 bool, count int) ([]int64, error) {
+	d := &orcIntDecoder{data: data, signed: signed}
+	out := make([]int64, 0, count)
+	for len(out) < count {
+		if d.pos >= len(d.data) {
+			return nil, fmt.Errorf("unexpected end of orc integer stream")
+		}
+		var err error
+		if v2 {
+			out, err = d.readRunV2(out)
+		} else {
+			out, err = d.readRunV1(out)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out[:count], nil
+}
+
+type orcIntDecoder struct {
+	data   []byte
+	pos    int
+	signed bool
+}
+
+func (d *orcIntDecoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, fmt.Errorf("unexpected end of orc integer stream")
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *orcIntDecoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid orc varint")
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *orcIntDecoder) svarint() (int64, error) {
+	v, err := d.uvarint()
+	return unZigZag(v), err
+}
+
+// value reads a varint according to the stream signedness
+func (d *orcIntDecoder) value() (int64, error) {
+	if d.signed {
+		return d.svarint()
+	}
+	v, err := d.uvarint()
+	return int64(v), err
+}
+
+func unZigZag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (d *orcIntDecoder) readRunV1(out []int64) ([]int64, error) {
+	control, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	if int8(control) >= 0 {
+		delta, err := d.byte()
+		if err != nil {
+			return nil, err
+		}
+		base, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < int(control)+3; i++ {
+			out = append(out, base+int64(i)*int64(int8(delta)))
+		}
+		return out, nil
+	}
+	for i := 0; i < -int(int8(control)); i++ {
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (d *orcIntDecoder) readRunV2(out []int64) ([]int64, error) {
+	header, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch header >> 6 {
+	case 0:
+		return d.readShortRepeat(header, out)
+	case 1:
+		return d.readDirect(header, out)
+	case 2:
+		return d.readPatchedBase(header, out)
+	}
+	return d.readDelta(header, out)
+}
+
+func (d *orcIntDecoder) readShortRepeat(header byte, out []int64) ([]int64, error) {
+	width := int((header>>3)&7) + 1
+	count := int(header&7) + 3
+	v, err := d.bigEndian(width)
+	if err != nil {
+		return nil, err
+	}
+	value := int64(v)
+	if d.signed {
+		value = unZigZag(v)
+	}
+	for i := 0; i < count; i++ {
+		out = append(out, value)
+	}
+	return out, nil
+}
+
+func (d *orcIntDecoder) readDirect(header byte, out []int64) ([]int64, error) {
+	width := decodeOrcBitWidth(int(header>>1) & 0x1f)
+	length, err := d.runLength(header)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := d.unpack(length, width)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vals {
+		if d.signed {
+			out = append(out, unZigZag(v))
+		} else {
+			out = append(out, int64(v))
+		}
+	}
+	return out, nil
+}
+
+func (d *orcIntDecoder) readPatchedBase(header byte, out []int64) ([]int64, error) {
+	width := decodeOrcBitWidth(int(header>>1) & 0x1f)
+	length, err := d.runLength(header)
+	if err != nil {
+		return nil, err
+	}
+	third, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	baseWidth := int(third>>5&7) + 1
+	patchWidth := decodeOrcBitWidth(int(third & 0x1f))
+	fourth, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	patchGapWidth := int(fourth>>5&7) + 1
+	patchListLen := int(fourth & 0x1f)
+
+	// the most significant bit of the base is its sign
+	b, err := d.bigEndian(baseWidth)
+	if err != nil {
+		return nil, err
+	}
+	signMask := uint64(1) << (baseWidth*8 - 1)
+	base := int64(b &^ signMask)
+	if b&signMask != 0 {
+		base = -base
+	}
+	vals, err := d.unpack(length, width)
+	if err != nil {
+		return nil, err
+	}
+	patches, err := d.unpack(patchListLen, closestOrcFixedBits(patchWidth+patchGapWidth))
+	if err != nil {
+		return nil, err
+	}
+
+	patchMask := uint64(1)<<patchWidth - 1
+	patchIdx := 0
+	// a gap longer than 255 is spread over entries with a zero patch
+	nextPatch := func(from int) (int, uint64, bool) {
+		gap := from
+		for patchIdx < len(patches) {
+			currGap, currPatch := int(patches[patchIdx]>>patchWidth), patches[patchIdx]&patchMask
+			if currGap == 255 && currPatch == 0 {
+				gap += 255
+				patchIdx++
+				continue
+			}
+			return gap + currGap, currPatch, true
+		}
+		return 0, 0, false
+	}
+	gap, patch, hasPatch := nextPatch(0)
+	for idx, v := range vals {
+		if hasPatch && idx == gap {
+			v |= patch << width
+			patchIdx++
+			gap, patch, hasPatch = nextPatch(idx)
+		}
+		out = append(out, base+int64(v))
+	}
+	return out, nil
+}
+
+func (d *orcIntDecoder) readDelta(header byte, out []int64) ([]int64, error) {
+	widthCode := int(header>>1) & 0x1f
+	width := 0
+	if widthCode != 0 {
+		width = decodeOrcBitWidth(widthCode)
+	}
+	length, err := d.runLength(header)
+	if err != nil {
+		return nil, err
+	}
+	base, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	deltaBase, err := d.svarint()
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, base)
+	if length == 1 {
+		return out, nil
+	}
+	// a zero width is a fixed delta run
+	if width == 0 {
+		for i := 1; i < length; i++ {
+			out = append(out, base+int64(i)*deltaBase)
+		}
+		return out, nil
+	}
+	prev := base + deltaBase
+	out = append(out, prev)
+	deltas, err := d.unpack(length-2, width)
+	if err != nil {
+		return nil, err
+	}
+	// the deltas are absolute values and take the sign of the delta base
+	for _, delta := range deltas {
+		if deltaBase < 0 {
+			prev -= int64(delta)
+		} else {
+			prev += int64(delta)
+		}
+		out = append(out, prev)
+	}
+	return out, nil
+}
+
+// runLength the 9 bits length of direct, patched base and delta runs
+func (d *orcIntDecoder) runLength(header byte) (int, error) {
+	low, err := d.byte()
+	if err != nil {
+		return 0, err
+	}
+	return (int(header&1)<<8 | int(low)) + 1, nil
+}
+
+func (d *orcIntDecoder) bigEndian(width int) (uint64, error) {
+	if d.pos+width > len(d.data) {
+		return 0, fmt.Errorf("unexpected end of orc integer stream")
+	}
+	var v uint64
+	for i := 0; i < width; i++ {
+		v = v<<8 | uint64(d.data[d.pos+i])
+	}
+	d.pos += width
+	return v, nil
+}
+
+// unpack reads count values of width bits, most significant bit first, padded to a full byte
+func (d *orcIntDecoder) unpack(count, width int) ([]uint64, error) {
+	byteLen := (count*width + 7) / 8
+	if d.pos+byteLen > len(d.data) {
+		return nil, fmt.Errorf("unexpected end of orc integer stream")
+	}
+	packed := d.data[d.pos : d.pos+byteLen]
+	d.pos += byteLen
+	out := make([]uint64, count)
+	bit := 0
+	for idx := range out {
+		var v uint64
+		for b := 0; b < width; b++ {
+			v = v<<1 | uint64(packed[bit/8]>>(7-bit%8)&1)
+			bit++
+		}
+		out[idx] = v
+	}
+	return out, nil
+}
+
+func decodeOrcBitWidth(code int) int {
+	switch {
+	case code <= 23:
+		return code + 1
+	case code == 24:
+		return 26
+	case code == 25:
+		return 28
+	case code == 26:
+		return 30
+	case code == 27:
+		return 32
+	case code == 28:
+		return 40
+	case code == 29:
+		return 48
+	case code == 30:
+		return 56
+	}
+	return 64
+}
+
+// closestOrcFixedBits rounds the patch entry width up to a width the bit packer supports
+func closestOrcFixedBits(n int) int {
+	switch {
+	case n == 0:
+		return 1
+	case n <= 24:
+		return n
+	}
+	for _, fixed := range []int{26, 28, 30, 32, 40, 48, 56} {
+		if n <= fixed {
+			return fixed
+		}
+	}
+	return 64
+}
+
+// orcMessage a decoded protobuf message, field number to its varint or bytes values
+type orcMessage map[protowire.Number][]any
+
+func parseOrcMessage(b []byte) (orcMessage, error) {
+	m := orcMessage{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			m[num] = append(m[num], v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			m[num] = append(m[num], v)
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return m, nil
+}
+
+func (m orcMessage) all(num protowire.Number) []any {
+	return m[num]
+}
+
+// uint the last varint value of the field, 0 when missing
+func (m orcMessage) uint(num protowire.Number) uint64 {
+	vals := m[num]
+	if len(vals) == 0 {
+		return 0
+	}
+	v, _ := vals[len(vals)-1].(uint64)
+	return v
+}
+
+// uints a repeated varint field, packed or not
+func (m orcMessage) uints(num protowire.Number) []uint64 {
+	var out []uint64
+	for _, v := range m[num] {
+		switch val := v.(type) {
+		case uint64:
+			out = append(out, val)
+		case []byte:
+			for len(val) > 0 {
+				x, n := protowire.ConsumeVarint(val)
+				if n < 0 {
+					break
+				}
+				out = append(out, x)
+				val = val[n:]
+			}
+		}
+	}
+	return out
+}
+
+func (m orcMessage) messages(num protowire.Number) ([]orcMessage, error) {
+	var out []orcMessage
+	for _, v := range m[num] {
+		b, ok := v.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("field %d is not a message", num)
+		}
+		msg, err := parseOrcMessage(b)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
@@ -0,0 +1,256 @@
+package s3search
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	common "github.com/isan-rivkin/surf/lib/search"
+	"github.com/magiconair/properties/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func pbVarint(b []byte, num protowire.Number, v uint64) []byte {
+	return protowire.AppendVarint(protowire.AppendTag(b, num, protowire.VarintType), v)
+}
+
+func pbBytes(b []byte, num protowire.Number, v []byte) []byte {
+	return protowire.AppendBytes(protowire.AppendTag(b, num, protowire.BytesType), v)
+}
+
+// orcDirectInts RLE v2 direct run of 64 bits values
+func orcDirectInts(signed bool, vals ...int64) []byte {
+	n := len(vals) - 1
+	b := []byte{0x40 | 31<<1 | byte(n>>8), byte(n)}
+	for _, v := range vals {
+		u := uint64(v)
+		if signed {
+			u = uint64(v<<1 ^ v>>63)
+		}
+		b = binary.BigEndian.AppendUint64(b, u)
+	}
+	return b
+}
+
+// orcPresent a single byte rle literal of up to 8 booleans
+func orcPresent(present ...bool) []byte {
+	var packed byte
+	for idx, p := range present {
+		if p {
+			packed |= 1 << (7 - idx)
+		}
+	}
+	return []byte{0xff, packed}
+}
+
+func orcZlibChunk(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	w.Close()
+	header := buf.Len() << 1
+	return append([]byte{byte(header), byte(header >> 8), byte(header >> 16)}, buf.Bytes()...)
+}
+
+type testOrcStream struct {
+	kind   uint64
+	column uint64
+	data   []byte
+}
+
+// testOrcInventory the rows of testParquetInventory in a zlib compressed orc file
+func testOrcInventory(t *testing.T) []byte {
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).Unix() - orcTimestampEpoch
+	streams := []testOrcStream{
+		{kind: 6, column: 0, data: []byte{1, 2, 3, 4, 5}},
+		{orcStreamData, 1, []byte("datalakedatalakedatalake")},
+		{orcStreamLength, 1, orcDirectInts(false, 8, 8, 8)},
+		{orcStreamData, 2, []byte("events/a.parquetevents/b c.parquetlogs/x.log")},
+		{orcStreamLength, 2, orcDirectInts(false, 16, 18, 10)},
+		{orcStreamPresent, 3, orcPresent(true, false, true)},
+		{orcStreamData, 3, orcDirectInts(true, 1024, 10)},
+		{orcStreamPresent, 4, orcPresent(true, true, false)},
+		{orcStreamData, 4, orcDirectInts(true, day, day+24*60*60)},
+		// 123 millis, 6 trailing zeros are encoded as 5
+		{orcStreamSecondary, 4, orcDirectInts(false, 0, 123<<3|5)},
+		{orcStreamData, 5, orcDirectInts(false, 0, 1, 0)},
+		{orcStreamLength, 5, orcDirectInts(false, 8, 7)},
+		{orcStreamDictionaryData, 5, []byte("STANDARDGLACIER")},
+	}
+
+	file := append([]byte{}, orcMagic...)
+	var stripeData, stripeFooter []byte
+	indexLen := 0
+	for idx, s := range streams {
+		data := s.data
+		if idx == 0 {
+			// index streams are not read, they only shift the data streams
+			indexLen = len(data)
+		} else {
+			data = orcZlibChunk(t, data)
+		}
+		stripeData = append(stripeData, data...)
+		var stream []byte
+		stream = pbVarint(stream, 1, s.kind)
+		stream = pbVarint(stream, 2, s.column)
+		stream = pbVarint(stream, 3, uint64(len(data)))
+		stripeFooter = pbBytes(stripeFooter, 1, stream)
+	}
+	for _, kind := range []uint64{orcDirect, orcDirectV2, orcDirectV2, orcDirectV2, orcDirectV2, orcDictionaryV2} {
+		encoding := pbVarint(nil, 1, kind)
+		if kind == orcDictionaryV2 {
+			encoding = pbVarint(encoding, 2, 2)
+		}
+		stripeFooter = pbBytes(stripeFooter, 2, encoding)
+	}
+	stripeFooter = orcZlibChunk(t, stripeFooter)
+	file = append(append(file, stripeData...), stripeFooter...)
+
+	var stripe []byte
+	stripe = pbVarint(stripe, 1, uint64(len(orcMagic)))
+	stripe = pbVarint(stripe, 2, uint64(indexLen))
+	stripe = pbVarint(stripe, 3, uint64(len(stripeData)-indexLen))
+	stripe = pbVarint(stripe, 4, uint64(len(stripeFooter)))
+	stripe = pbVarint(stripe, 5, 3)
+
+	var subtypes, root []byte
+	for id := uint64(1); id <= 5; id++ {
+		subtypes = protowire.AppendVarint(subtypes, id)
+	}
+	root = pbVarint(root, 1, orcStruct)
+	root = pbBytes(root, 2, subtypes)
+	for _, name := range []string{"bucket", "key", "size", "last_modified_date", "storage_class"} {
+		root = pbBytes(root, 3, []byte(name))
+	}
+
+	var footer []byte
+	footer = pbVarint(footer, 1, uint64(len(orcMagic)))
+	footer = pbVarint(footer, 2, uint64(len(file)-len(orcMagic)))
+	footer = pbBytes(footer, 3, stripe)
+	footer = pbBytes(footer, 4, root)
+	for _, kind := range []uint64{orcString, orcString, orcLong, orcTimestamp, orcVarchar} {
+		footer = pbBytes(footer, 4, pbVarint(nil, 1, kind))
+	}
+	footer = pbVarint(footer, 6, 3)
+	footer = orcZlibChunk(t, footer)
+	file = append(file, footer...)
+
+	var ps []byte
+	ps = pbVarint(ps, 1, uint64(len(footer)))
+	ps = pbVarint(ps, 2, orcZlib)
+	ps = pbVarint(ps, 3, 256*1024)
+	ps = pbBytes(ps, 8000, orcMagic)
+	file = append(file, ps...)
+	return append(file, byte(len(ps)))
+}
+
+func TestDecodeOrcIntsV2(t *testing.T) {
+	// the examples of the orc spec
+	tests := []struct {
+		name     string
+		data     []byte
+		expected []int64
+	}{
+		{"short repeat", []byte{0x0a, 0x27, 0x10}, []int64{10000, 10000, 10000, 10000, 10000}},
+		{"direct", []byte{0x5e, 0x03, 0x5c, 0xa1, 0xab, 0x1e, 0xde, 0xad, 0xbe, 0xef}, []int64{23713, 43806, 57005, 48879}},
+		{"patched base", []byte{0x8e, 0x13, 0x2b, 0x21, 0x07, 0xd0, 0x1e, 0x00, 0x14, 0x70, 0x28, 0x32, 0x3c, 0x46, 0x50, 0x5a, 0x64, 0x6e, 0x78, 0x82, 0x8c, 0x96, 0xa0, 0xaa, 0xb4, 0xbe, 0xfc, 0xe8},
+			[]int64{2030, 2000, 2020, 1000000, 2040, 2050, 2060, 2070, 2080, 2090, 2100, 2110, 2120, 2130, 2140, 2150, 2160, 2170, 2180, 2190}},
+		{"delta", []byte{0xc6, 0x09, 0x02, 0x02, 0x22, 0x42, 0x42, 0x46}, []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}},
+	}
+	for _, test := range tests {
+		vals, err := decodeOrcInts(test.data, false, true, len(test.expected))
+		assert.Equal(t, err, nil, test.name)
+		assert.Equal(t, vals, test.expected, test.name)
+	}
+
+	// signed values are zigzag encoded
+	vals, err := decodeOrcInts(orcDirectInts(true, -5, 7), true, true, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, vals, []int64{-5, 7})
+
+	_, err = decodeOrcInts([]byte{0x5e, 0x03, 0x5c}, false, true, 4)
+	assert.Equal(t, err != nil, true)
+}
+
+func TestDecodeOrcIntsV1(t *testing.T) {
+	// a run of 100 decreasing by 1 from 100 and the literals 2, 3, 6, 7, 11
+	vals, err := decodeOrcInts([]byte{0x61, 0xff, 0x64, 0xfb, 0x02, 0x03, 0x06, 0x07, 0x0b}, false, false, 105)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, vals[0], int64(100))
+	assert.Equal(t, vals[99], int64(1))
+	assert.Equal(t, vals[100:], []int64{2, 3, 6, 7, 11})
+}
+
+func TestDecodeOrcByteRLE(t *testing.T) {
+	vals, err := decodeOrcByteRLE([]byte{0x61, 0x00}, 100)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(vals), 100)
+	assert.Equal(t, vals[99], byte(0))
+
+	vals, err = decodeOrcByteRLE([]byte{0xfe, 0x44, 0x45}, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, vals, []byte{0x44, 0x45})
+
+	bools, err := decodeOrcBooleans([]byte{0xff, 0x80}, 3)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, bools, []bool{true, false, false})
+}
+
+func TestOrcInventorySearch(t *testing.T) {
+	file := testOrcInventory(t)
+	m := common.NewDefaultRegexMatcher()
+
+	// remote files are spooled to a temp file
+	matches, err := searchInventoryDataFile(bytes.NewReader(file), InventoryFormatORC, nil, []string{"Key"}, `\.parquet$`, m)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 2)
+	assert.Equal(t, matches[0].Fields, map[string]string{
+		"Bucket":           "datalake",
+		"Key":              "events/a.parquet",
+		"Size":             "1024",
+		"LastModifiedDate": "2023-01-01T00:00:00.000Z",
+		"StorageClass":     "STANDARD",
+	})
+	_, exist := matches[1].Fields["Size"]
+	assert.Equal(t, exist, false)
+	assert.Equal(t, matches[1].Fields["LastModifiedDate"], "2023-01-02T00:00:00.123Z")
+
+	matches, err = SearchColumnarInventoryFile(bytes.NewReader(file), int64(len(file)), InventoryFormatORC, []string{"Key", "StorageClass"}, `^GLACIER$`, m)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 1)
+	assert.Equal(t, matches[0].Key, "events/b c.parquet")
+	assert.Equal(t, matches[0].Column, "StorageClass")
+
+	_, err = SearchColumnarInventoryFile(bytes.NewReader(file[:10]), 10, InventoryFormatORC, nil, `.`, m)
+	assert.Equal(t, err != nil, true)
+}
+
+func TestLocalColumnarInventorySearch(t *testing.T) {
+	files := map[string][]byte{
+		InventoryFormatORC:     testOrcInventory(t),
+		InventoryFormatParquet: testParquetInventory(),
+	}
+	for format, data := range files {
+		dir := t.TempDir()
+		name := "part-1." + strings.ToLower(format)
+		manifest := `{"sourceBucket": "datalake", "destinationBucket": "arn:aws:s3:::inventories", "fileFormat": "` + format + `",
+			"fileSchema": "message s3.inventory { required binary bucket (UTF8); }", "files": [{"key": "inventory/datalake/daily/data/` + name + `"}]}`
+		assert.Equal(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0644), nil)
+		assert.Equal(t, os.WriteFile(filepath.Join(dir, name), data, 0644), nil)
+
+		s := NewInventorySearcher(nil, common.NewDefaultRegexMatcher())
+		out, err := s.Search(&InventoryInput{Manifest: dir, Value: `^STANDARD$`, Columns: []string{"StorageClass"}, Parallel: 1})
+		assert.Equal(t, err, nil, format)
+		assert.Equal(t, len(out.Matches), 2, format)
+		assert.Equal(t, out.Matches[1].Key, "logs/x.log", format)
+		assert.Equal(t, out.Matches[1].Fields["Size"], "10", format)
+	}
+}
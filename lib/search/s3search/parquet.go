@@ -0,0 +1,744 @@
+package s3search
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// a minimal parquet reader for flat schemas like S3 Inventory reports, see https://github.com/apache/parquet-format
+
+var parquetMagic = []byte("PAR1")
+
+// parquet physical types
+const (
+	parquetBoolean   = 0
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetInt96     = 3
+	parquetFloat     = 4
+	parquetDouble    = 5
+	parquetByteArray = 6
+	parquetFixedLen  = 7
+)
+
+// parquet codecs, encodings and page types
+const (
+	parquetUncompressed = 0
+	parquetSnappy       = 1
+	parquetGzip         = 2
+	parquetZstd         = 6
+
+	parquetPlain          = 0
+	parquetPlainDict      = 2
+	parquetRLEDictionary  = 8
+	parquetDataPage       = 0
+	parquetDictionaryPage = 2
+	parquetDataPageV2     = 3
+
+	parquetOptional = 1
+	parquetRepeated = 2
+
+	parquetConvertedDate            = 6
+	parquetConvertedTimestampMillis = 9
+	parquetConvertedTimestampMicros = 10
+)
+
+// parquetColumn a leaf of the schema
+type parquetColumn struct {
+	path       string
+	physical   int64
+	typeLength int64
+	// 0 for required columns, 1 for optional columns of a flat schema
+	maxDef int
+	// nested repeated columns are not supported
+	repeated bool
+	// MILLIS, MICROS or NANOS for timestamps, empty otherwise
+	timeUnit string
+	isDate   bool
+}
+
+// readParquetRows calls onRow with every row, null values are left out of the row
+func readParquetRows(r io.ReaderAt, size int64, onRow func(map[string]string) error) error {
+	if size < int64(len(parquetMagic))*2+4 {
+		return fmt.Errorf("failed reading parquet, file too small")
+	}
+	tail := make([]byte, 8)
+	if _, err := r.ReadAt(tail, size-8); err != nil {
+		return fmt.Errorf("failed reading parquet footer %s", err.Error())
+	}
+	if !bytes.Equal(tail[4:], parquetMagic) {
+		return fmt.Errorf("failed reading parquet, not a parquet file")
+	}
+	footerLen := int64(binary.LittleEndian.Uint32(tail[:4]))
+	if footerLen > size-8 {
+		return fmt.Errorf("failed reading parquet, invalid footer length %d", footerLen)
+	}
+	footer := make([]byte, footerLen)
+	if _, err := r.ReadAt(footer, size-8-footerLen); err != nil {
+		return fmt.Errorf("failed reading parquet footer %s", err.Error())
+	}
+	meta, _, err := readThriftStruct(footer)
+	if err != nil {
+		return fmt.Errorf("failed parsing parquet footer %s", err.Error())
+	}
+	columns, err := parquetSchemaColumns(meta.list(2))
+	if err != nil {
+		return err
+	}
+
+	for _, rg := range meta.list(4) {
+		rowGroup, ok := rg.(thriftStruct)
+		if !ok {
+			return fmt.Errorf("failed parsing parquet row group")
+		}
+		numRows := int(rowGroup.int(3))
+		values := map[string][]*string{}
+		for _, cc := range rowGroup.list(1) {
+			chunk, ok := cc.(thriftStruct)
+			if !ok {
+				return fmt.Errorf("failed parsing parquet column chunk")
+			}
+			chunkMeta := chunk.strct(3)
+			var path []string
+			for _, p := range chunkMeta.list(3) {
+				if b, ok := p.([]byte); ok {
+					path = append(path, string(b))
+				}
+			}
+			col, exist := columns[strings.Join(path, ".")]
+			if !exist || col.repeated {
+				continue
+			}
+			vals, err := readParquetColumnChunk(r, size, col, chunkMeta)
+			if err != nil {
+				return fmt.Errorf("failed reading parquet column %s %s", col.path, err.Error())
+			}
+			values[col.path] = vals
+		}
+		for row := 0; row < numRows; row++ {
+			fields := map[string]string{}
+			for path, vals := range values {
+				if row < len(vals) && vals[row] != nil {
+					fields[path] = *vals[row]
+				}
+			}
+			if err := onRow(fields); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parquetSchemaColumns walks the depth first schema list, the first element is the root
+func parquetSchemaColumns(schema []any) (map[string]*parquetColumn, error) {
+	columns := map[string]*parquetColumn{}
+	idx := 0
+	var walk func(prefix []string, def int, repeated bool) error
+	walk = func(prefix []string, def int, repeated bool) error {
+		if idx >= len(schema) {
+			return fmt.Errorf("failed parsing parquet schema, missing elements")
+		}
+		el, ok := schema[idx].(thriftStruct)
+		if !ok {
+			return fmt.Errorf("failed parsing parquet schema element")
+		}
+		idx++
+		name := string(el.bytes(4))
+		path := prefix
+		// the root name is not part of the column paths
+		if idx > 1 {
+			path = append(append([]string{}, prefix...), name)
+			switch el.int(3) {
+			case parquetOptional:
+				def++
+			case parquetRepeated:
+				def++
+				repeated = true
+			}
+		}
+		if children := int(el.int(5)); children > 0 {
+			for c := 0; c < children; c++ {
+				if err := walk(path, def, repeated); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		col := &parquetColumn{
+			path:       strings.Join(path, "."),
+			physical:   el.int(1),
+			typeLength: el.int(2),
+			maxDef:     def,
+			repeated:   repeated || def > 1,
+			isDate:     el.int(6) == parquetConvertedDate && el.has(6),
+		}
+		switch {
+		case el.has(6) && el.int(6) == parquetConvertedTimestampMillis:
+			col.timeUnit = "MILLIS"
+		case el.has(6) && el.int(6) == parquetConvertedTimestampMicros:
+			col.timeUnit = "MICROS"
+		}
+		// LogicalType union, 8 is TIMESTAMP with the unit union in field 2
+		if ts := el.strct(10).strct(8); ts != nil {
+			unit := ts.strct(2)
+			switch {
+			case unit.has(1):
+				col.timeUnit = "MILLIS"
+			case unit.has(2):
+				col.timeUnit = "MICROS"
+			case unit.has(3):
+				col.timeUnit = "NANOS"
+			}
+		}
+		columns[col.path] = col
+		return nil
+	}
+	if err := walk(nil, 0, false); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+func readParquetColumnChunk(r io.ReaderAt, size int64, col *parquetColumn, meta thriftStruct) ([]*string, error) {
+	codec := meta.int(4)
+	numValues := int(meta.int(5))
+	start := meta.int(9)
+	if dictOffset := meta.int(11); meta.has(11) && dictOffset > 0 && dictOffset < start {
+		start = dictOffset
+	}
+	length := meta.int(7)
+	if start < 0 || length < 0 || start+length > size {
+		return nil, fmt.Errorf("invalid column chunk range")
+	}
+	chunk := make([]byte, length)
+	if _, err := r.ReadAt(chunk, start); err != nil {
+		return nil, err
+	}
+
+	var dict []string
+	values := make([]*string, 0, numValues)
+	for len(values) < numValues && len(chunk) > 0 {
+		header, n, err := readThriftStruct(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing page header %s", err.Error())
+		}
+		chunk = chunk[n:]
+		compressedSize := int(header.int(3))
+		if compressedSize < 0 || compressedSize > len(chunk) {
+			return nil, fmt.Errorf("invalid page size %d", compressedSize)
+		}
+		page := chunk[:compressedSize]
+		chunk = chunk[compressedSize:]
+
+		switch header.int(1) {
+		case parquetDictionaryPage:
+			data, err := parquetDecompress(codec, page, int(header.int(2)))
+			if err != nil {
+				return nil, err
+			}
+			dict, _, err = decodeParquetPlain(data, col, int(header.strct(7).int(1)))
+			if err != nil {
+				return nil, err
+			}
+		case parquetDataPage:
+			data, err := parquetDecompress(codec, page, int(header.int(2)))
+			if err != nil {
+				return nil, err
+			}
+			dph := header.strct(5)
+			count := int(dph.int(1))
+			var defLevels []int
+			if col.maxDef > 0 {
+				if len(data) < 4 {
+					return nil, fmt.Errorf("invalid definition levels")
+				}
+				levelsLen := int(binary.LittleEndian.Uint32(data))
+				if levelsLen > len(data)-4 {
+					return nil, fmt.Errorf("invalid definition levels length %d", levelsLen)
+				}
+				if defLevels, err = decodeRLEHybrid(data[4:4+levelsLen], bits.Len(uint(col.maxDef)), count); err != nil {
+					return nil, err
+				}
+				data = data[4+levelsLen:]
+			}
+			if values, err = appendParquetValues(values, data, col, dph.int(2), count, defLevels, dict); err != nil {
+				return nil, err
+			}
+		case parquetDataPageV2:
+			dph := header.strct(8)
+			count := int(dph.int(1))
+			repLen, defLen := int(dph.int(6)), int(dph.int(5))
+			if repLen+defLen > len(page) {
+				return nil, fmt.Errorf("invalid levels length")
+			}
+			var defLevels []int
+			if col.maxDef > 0 {
+				if defLevels, err = decodeRLEHybrid(page[repLen:repLen+defLen], bits.Len(uint(col.maxDef)), count); err != nil {
+					return nil, err
+				}
+			}
+			data := page[repLen+defLen:]
+			// levels are never compressed, values are unless is_compressed is false
+			if !dph.has(7) || dph.bool(7) {
+				if data, err = parquetDecompress(codec, data, int(header.int(2))-repLen-defLen); err != nil {
+					return nil, err
+				}
+			}
+			if values, err = appendParquetValues(values, data, col, dph.int(4), count, defLevels, dict); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return values, nil
+}
+
+// appendParquetValues decodes the values of a data page, defLevels lower than maxDef are nulls without a value
+func appendParquetValues(values []*string, data []byte, col *parquetColumn, encoding int64, count int, defLevels []int, dict []string) ([]*string, error) {
+	nonNull := count
+	if defLevels != nil {
+		nonNull = 0
+		for _, l := range defLevels {
+			if l == col.maxDef {
+				nonNull++
+			}
+		}
+	}
+	var decoded []string
+	switch encoding {
+	case parquetPlain:
+		var err error
+		if decoded, _, err = decodeParquetPlain(data, col, nonNull); err != nil {
+			return nil, err
+		}
+	case parquetPlainDict, parquetRLEDictionary:
+		if len(data) < 1 {
+			return nil, fmt.Errorf("invalid dictionary indexes")
+		}
+		indexes, err := decodeRLEHybrid(data[1:], int(data[0]), nonNull)
+		if err != nil {
+			return nil, err
+		}
+		for _, idx := range indexes {
+			if idx < 0 || idx >= len(dict) {
+				return nil, fmt.Errorf("dictionary index %d out of range", idx)
+			}
+			decoded = append(decoded, dict[idx])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported parquet encoding %d", encoding)
+	}
+	next := 0
+	for i := 0; i < count; i++ {
+		if defLevels != nil && defLevels[i] < col.maxDef {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, &decoded[next])
+		next++
+	}
+	return values, nil
+}
+
+// decodeParquetPlain decodes count PLAIN values and returns them as text
+func decodeParquetPlain(data []byte, col *parquetColumn, count int) ([]string, int, error) {
+	out := make([]string, 0, count)
+	pos := 0
+	need := func(n int) error {
+		if n < 0 || pos+n > len(data) {
+			return fmt.Errorf("unexpected end of parquet page")
+		}
+		return nil
+	}
+	for i := 0; i < count; i++ {
+		switch col.physical {
+		case parquetBoolean:
+			if err := need((i / 8) + 1 - pos); err != nil {
+				return nil, 0, err
+			}
+			out = append(out, strconv.FormatBool(data[i/8]>>(i%8)&1 == 1))
+			continue
+		case parquetInt32:
+			if err := need(4); err != nil {
+				return nil, 0, err
+			}
+			v := int32(binary.LittleEndian.Uint32(data[pos:]))
+			pos += 4
+			if col.isDate {
+				out = append(out, time.Unix(int64(v)*24*60*60, 0).UTC().Format("2006-01-02"))
+			} else {
+				out = append(out, strconv.FormatInt(int64(v), 10))
+			}
+		case parquetInt64:
+			if err := need(8); err != nil {
+				return nil, 0, err
+			}
+			v := int64(binary.LittleEndian.Uint64(data[pos:]))
+			pos += 8
+			out = append(out, formatParquetInt64(v, col.timeUnit))
+		case parquetInt96:
+			if err := need(12); err != nil {
+				return nil, 0, err
+			}
+			nanos := int64(binary.LittleEndian.Uint64(data[pos:]))
+			julianDay := int64(binary.LittleEndian.Uint32(data[pos+8:]))
+			pos += 12
+			// julian day 2440588 is the unix epoch
+			out = append(out, formatInventoryTime(time.Unix((julianDay-2440588)*24*60*60, nanos)))
+		case parquetFloat:
+			if err := need(4); err != nil {
+				return nil, 0, err
+			}
+			out = append(out, strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(data[pos:]))), 'g', -1, 32))
+			pos += 4
+		case parquetDouble:
+			if err := need(8); err != nil {
+				return nil, 0, err
+			}
+			out = append(out, strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(data[pos:])), 'g', -1, 64))
+			pos += 8
+		case parquetByteArray:
+			if err := need(4); err != nil {
+				return nil, 0, err
+			}
+			n := int(binary.LittleEndian.Uint32(data[pos:]))
+			pos += 4
+			if err := need(n); err != nil {
+				return nil, 0, err
+			}
+			out = append(out, string(data[pos:pos+n]))
+			pos += n
+		case parquetFixedLen:
+			n := int(col.typeLength)
+			if err := need(n); err != nil {
+				return nil, 0, err
+			}
+			out = append(out, string(data[pos:pos+n]))
+			pos += n
+		default:
+			return nil, 0, fmt.Errorf("unsupported parquet type %d", col.physical)
+		}
+	}
+	if col.physical == parquetBoolean {
+		pos = (count + 7) / 8
+	}
+	return out, pos, nil
+}
+
+func formatParquetInt64(v int64, unit string) string {
+	switch unit {
+	case "MILLIS":
+		return formatInventoryTime(time.UnixMilli(v))
+	case "MICROS":
+		return formatInventoryTime(time.UnixMicro(v))
+	case "NANOS":
+		return formatInventoryTime(time.Unix(0, v))
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+// formatInventoryTime the CSV inventory format of dates i.e 2023-01-02T00:00:00.000Z
+func formatInventoryTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func parquetDecompress(codec int64, data []byte, uncompressedSize int) ([]byte, error) {
+	switch codec {
+	case parquetUncompressed:
+		return data, nil
+	case parquetSnappy:
+		return snappy.Decode(nil, data)
+	case parquetGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		return io.ReadAll(gr)
+	case parquetZstd:
+		dec, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		return dec.DecodeAll(data, make([]byte, 0, uncompressedSize))
+	}
+	return nil, fmt.Errorf("unsupported parquet compression codec %d", codec)
+}
+
+// decodeRLEHybrid the RLE / bit packed hybrid encoding of levels and dictionary indexes
+func decodeRLEHybrid(data []byte, bitWidth, count int) ([]int, error) {
+	if bitWidth < 0 || bitWidth > 32 {
+		return nil, fmt.Errorf("invalid bit width %d", bitWidth)
+	}
+	out := make([]int, 0, count)
+	pos := 0
+	for len(out) < count {
+		header, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid rle run header")
+		}
+		pos += n
+		if header&1 == 1 {
+			// bit packed groups of 8 values, least significant bit first
+			values := int(header>>1) * 8
+			byteLen := values * bitWidth / 8
+			if pos+byteLen > len(data) {
+				return nil, fmt.Errorf("unexpected end of bit packed run")
+			}
+			packed := data[pos : pos+byteLen]
+			pos += byteLen
+			for v := 0; v < values && len(out) < count; v++ {
+				val := 0
+				for b := 0; b < bitWidth; b++ {
+					bit := v*bitWidth + b
+					val |= int(packed[bit/8]>>(bit%8)&1) << b
+				}
+				out = append(out, val)
+			}
+			continue
+		}
+		runLen := int(header >> 1)
+		byteLen := (bitWidth + 7) / 8
+		if pos+byteLen > len(data) {
+			return nil, fmt.Errorf("unexpected end of rle run")
+		}
+		val := 0
+		for b := 0; b < byteLen; b++ {
+			val |= int(data[pos+b]) << (8 * b)
+		}
+		pos += byteLen
+		for i := 0; i < runLen && len(out) < count; i++ {
+			out = append(out, val)
+		}
+	}
+	return out, nil
+}
+
+// thriftStruct a decoded thrift compact protocol struct, field id to value
+type thriftStruct map[int16]any
+
+func (s thriftStruct) has(id int16) bool {
+	_, exist := s[id]
+	return exist
+}
+
+func (s thriftStruct) int(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStruct) bool(id int16) bool {
+	v, _ := s[id].(bool)
+	return v
+}
+
+func (s thriftStruct) bytes(id int16) []byte {
+	v, _ := s[id].([]byte)
+	return v
+}
+
+func (s thriftStruct) list(id int16) []any {
+	v, _ := s[id].([]any)
+	return v
+}
+
+// strct nil safe so nested optional structs can be chained
+func (s thriftStruct) strct(id int16) thriftStruct {
+	if s == nil {
+		return nil
+	}
+	v, _ := s[id].(thriftStruct)
+	return v
+}
+
+// thrift compact protocol types
+const (
+	thriftBoolTrue  = 1
+	thriftBoolFalse = 2
+	thriftByte      = 3
+	thriftI16       = 4
+	thriftI32       = 5
+	thriftI64       = 6
+	thriftDouble    = 7
+	thriftBinary    = 8
+	thriftList      = 9
+	thriftSet       = 10
+	thriftMap       = 11
+	thriftStructT   = 12
+)
+
+type thriftReader struct {
+	data []byte
+	pos  int
+	// nesting guard against malformed input
+	depth int
+}
+
+// readThriftStruct decodes a struct and returns how many bytes it used
+func readThriftStruct(data []byte) (thriftStruct, int, error) {
+	r := &thriftReader{data: data}
+	s, err := r.readStruct()
+	if err != nil {
+		return nil, 0, err
+	}
+	return s, r.pos, nil
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, fmt.Errorf("unexpected end of thrift data")
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *thriftReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid thrift varint")
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *thriftReader) varint() (int64, error) {
+	v, err := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *thriftReader) readStruct() (thriftStruct, error) {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > 64 {
+		return nil, fmt.Errorf("thrift struct nested too deep")
+	}
+	s := thriftStruct{}
+	var lastID int16
+	for {
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if header == 0 {
+			return s, nil
+		}
+		typ := header & 0x0f
+		id := lastID + int16(header>>4)
+		if header>>4 == 0 {
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		lastID = id
+		var val any
+		switch typ {
+		case thriftBoolTrue:
+			val = true
+		case thriftBoolFalse:
+			val = false
+		default:
+			if val, err = r.readValue(typ); err != nil {
+				return nil, err
+			}
+		}
+		s[id] = val
+	}
+}
+
+func (r *thriftReader) readValue(typ byte) (any, error) {
+	switch typ {
+	case thriftBoolTrue, thriftBoolFalse:
+		// bool list elements are a byte each
+		b, err := r.byte()
+		return b == thriftBoolTrue, err
+	case thriftByte:
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return r.varint()
+	case thriftDouble:
+		if r.pos+8 > len(r.data) {
+			return nil, fmt.Errorf("unexpected end of thrift data")
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.pos:]))
+		r.pos += 8
+		return v, nil
+	case thriftBinary:
+		n, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(r.data)-r.pos) {
+			return nil, fmt.Errorf("unexpected end of thrift data")
+		}
+		b := r.data[r.pos : r.pos+int(n)]
+		r.pos += int(n)
+		return b, nil
+	case thriftList, thriftSet:
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = r.uvarint(); err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(len(r.data)-r.pos) {
+			return nil, fmt.Errorf("invalid thrift list size %d", size)
+		}
+		list := make([]any, 0, size)
+		for i := uint64(0); i < size; i++ {
+			v, err := r.readValue(header & 0x0f)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case thriftMap:
+		size, err := r.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return map[any]any{}, nil
+		}
+		if size > uint64(len(r.data)-r.pos) {
+			return nil, fmt.Errorf("invalid thrift map size %d", size)
+		}
+		types, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		// map values are only skipped, parquet metadata maps are not used
+		for i := uint64(0); i < size; i++ {
+			if _, err := r.readValue(types >> 4); err != nil {
+				return nil, err
+			}
+			if _, err := r.readValue(types & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return map[any]any{}, nil
+	case thriftStructT:
+		return r.readStruct()
+	}
+	return nil, fmt.Errorf("unknown thrift type %d", typ)
+}
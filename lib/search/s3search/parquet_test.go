@@ -0,0 +1,260 @@
+package s3search
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	common "github.com/isan-rivkin/surf/lib/search"
+	"github.com/magiconair/properties/assert"
+)
+
+// thrift compact writer for the test files
+type tField struct {
+	id  int16
+	typ byte
+	v   any
+}
+
+type tList struct {
+	typ   byte
+	elems []any
+}
+
+func thriftEncode(fields []tField) []byte {
+	var b []byte
+	var last int16
+	for _, f := range fields {
+		b = append(b, byte(f.id-last)<<4|f.typ)
+		last = f.id
+		b = thriftAppend(b, f.typ, f.v)
+	}
+	return append(b, 0)
+}
+
+func thriftAppend(b []byte, typ byte, v any) []byte {
+	switch typ {
+	case thriftI32, thriftI64:
+		n := v.(int64)
+		return binary.AppendUvarint(b, uint64(n<<1^n>>63))
+	case thriftBinary:
+		data := v.([]byte)
+		return append(binary.AppendUvarint(b, uint64(len(data))), data...)
+	case thriftStructT:
+		return append(b, thriftEncode(v.([]tField))...)
+	case thriftList:
+		l := v.(tList)
+		if len(l.elems) < 15 {
+			b = append(b, byte(len(l.elems))<<4|l.typ)
+		} else {
+			b = binary.AppendUvarint(append(b, 0xf0|l.typ), uint64(len(l.elems)))
+		}
+		for _, e := range l.elems {
+			b = thriftAppend(b, l.typ, e)
+		}
+	}
+	return b
+}
+
+func plainStrings(vals ...string) []byte {
+	var b []byte
+	for _, v := range vals {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(v)))
+		b = append(b, v...)
+	}
+	return b
+}
+
+func plainInt64s(vals ...int64) []byte {
+	var b []byte
+	for _, v := range vals {
+		b = binary.LittleEndian.AppendUint64(b, uint64(v))
+	}
+	return b
+}
+
+// bitPackedLevels definition levels of width 1 as a single bit packed run with the v1 length prefix
+func bitPackedLevels(levels ...int) []byte {
+	groups := (len(levels) + 7) / 8
+	packed := make([]byte, groups)
+	for idx, l := range levels {
+		packed[idx/8] |= byte(l) << (idx % 8)
+	}
+	run := append([]byte{byte(groups<<1 | 1)}, packed...)
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(run))), run...)
+}
+
+func dataPageV1(rows int, encoding int64, body, compressed []byte) []byte {
+	header := thriftEncode([]tField{
+		{1, thriftI32, int64(parquetDataPage)},
+		{2, thriftI32, int64(len(body))},
+		{3, thriftI32, int64(len(compressed))},
+		{5, thriftStructT, []tField{
+			{1, thriftI32, int64(rows)},
+			{2, thriftI32, encoding},
+			{3, thriftI32, int64(3)},
+			{4, thriftI32, int64(3)},
+		}},
+	})
+	return append(header, compressed...)
+}
+
+type testParquetColumn struct {
+	schema []tField
+	name   string
+	typ    int64
+	codec  int64
+	// the column chunk, dictionary page first
+	pages          []byte
+	dictionaryPage bool
+}
+
+func buildParquetFile(rows int, columns []testParquetColumn) []byte {
+	file := append([]byte{}, parquetMagic...)
+	schema := []any{[]tField{{4, thriftBinary, []byte("schema")}, {5, thriftI32, int64(len(columns))}}}
+	var chunks []any
+	for _, c := range columns {
+		offset := int64(len(file))
+		file = append(file, c.pages...)
+		meta := []tField{
+			{1, thriftI32, c.typ},
+			{3, thriftList, tList{thriftBinary, []any{[]byte(c.name)}}},
+			{4, thriftI32, c.codec},
+			{5, thriftI64, int64(rows)},
+			{7, thriftI64, int64(len(c.pages))},
+			{9, thriftI64, offset},
+		}
+		if c.dictionaryPage {
+			meta = append(meta, tField{11, thriftI64, offset})
+		}
+		chunks = append(chunks, []tField{{2, thriftI64, offset}, {3, thriftStructT, meta}})
+		schema = append(schema, c.schema)
+	}
+	footer := thriftEncode([]tField{
+		{1, thriftI32, int64(1)},
+		{2, thriftList, tList{thriftStructT, schema}},
+		{3, thriftI64, int64(rows)},
+		{4, thriftList, tList{thriftStructT, []any{[]tField{
+			{1, thriftList, tList{thriftStructT, chunks}},
+			{3, thriftI64, int64(rows)},
+		}}}},
+	})
+	file = append(file, footer...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(footer)))
+	return append(file, parquetMagic...)
+}
+
+// testParquetInventory rows:
+// datalake, events/a.parquet, 1024, 2023-01-01, STANDARD
+// datalake, events/b c.parquet, null, 2023-01-02, GLACIER
+// datalake, logs/x.log, 10, null, STANDARD
+func testParquetInventory() []byte {
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	bucketValues := plainStrings("datalake", "datalake", "datalake")
+	// data page v2 without levels
+	keyValues := plainStrings("events/a.parquet", "events/b c.parquet", "logs/x.log")
+	keyPage := append(thriftEncode([]tField{
+		{1, thriftI32, int64(parquetDataPageV2)},
+		{2, thriftI32, int64(len(keyValues))},
+		{3, thriftI32, int64(len(keyValues))},
+		{8, thriftStructT, []tField{
+			{1, thriftI32, int64(3)},
+			{2, thriftI32, int64(0)},
+			{3, thriftI32, int64(3)},
+			{4, thriftI32, int64(parquetPlain)},
+			{5, thriftI32, int64(0)},
+			{6, thriftI32, int64(0)},
+		}},
+	}), keyValues...)
+	sizeBody := append(bitPackedLevels(1, 0, 1), plainInt64s(1024, 10)...)
+	dateBody := append(bitPackedLevels(1, 1, 0), plainInt64s(day.UnixMilli(), day.AddDate(0, 0, 1).UnixMilli())...)
+
+	// snappy compressed and dictionary encoded
+	dict := plainStrings("STANDARD", "GLACIER")
+	dictPage := append(thriftEncode([]tField{
+		{1, thriftI32, int64(parquetDictionaryPage)},
+		{2, thriftI32, int64(len(dict))},
+		{3, thriftI32, int64(len(snappy.Encode(nil, dict)))},
+		{7, thriftStructT, []tField{{1, thriftI32, int64(2)}, {2, thriftI32, int64(parquetPlain)}}},
+	}), snappy.Encode(nil, dict)...)
+	// bit width 1, one bit packed group of the indexes 0, 1, 0
+	classBody := append(bitPackedLevels(1, 1, 1), 1, 3, 0x02)
+	classPage := dataPageV1(3, parquetRLEDictionary, classBody, snappy.Encode(nil, classBody))
+
+	optional := func(name string, typ int64, extra ...tField) []tField {
+		return append([]tField{{1, thriftI32, typ}, {3, thriftI32, int64(parquetOptional)}, {4, thriftBinary, []byte(name)}}, extra...)
+	}
+	return buildParquetFile(3, []testParquetColumn{
+		{
+			name: "bucket", typ: parquetByteArray, pages: dataPageV1(3, parquetPlain, bucketValues, bucketValues),
+			schema: []tField{{1, thriftI32, int64(parquetByteArray)}, {3, thriftI32, int64(0)}, {4, thriftBinary, []byte("bucket")}, {6, thriftI32, int64(0)}},
+		},
+		{
+			name: "key", typ: parquetByteArray, pages: keyPage,
+			schema: []tField{{1, thriftI32, int64(parquetByteArray)}, {3, thriftI32, int64(0)}, {4, thriftBinary, []byte("key")}},
+		},
+		{
+			name: "size", typ: parquetInt64, pages: dataPageV1(3, parquetPlain, sizeBody, sizeBody),
+			schema: optional("size", parquetInt64),
+		},
+		{
+			name: "last_modified_date", typ: parquetInt64, pages: dataPageV1(3, parquetPlain, dateBody, dateBody),
+			// TIMESTAMP(isAdjustedToUTC=true, unit=MILLIS) logical type
+			schema: optional("last_modified_date", parquetInt64, tField{10, thriftStructT, []tField{
+				{8, thriftStructT, []tField{{1, thriftBoolTrue, nil}, {2, thriftStructT, []tField{{1, thriftStructT, []tField{}}}}}},
+			}}),
+		},
+		{
+			name: "storage_class", typ: parquetByteArray, codec: parquetSnappy, pages: append(dictPage, classPage...), dictionaryPage: true,
+			schema: optional("storage_class", parquetByteArray, tField{6, thriftI32, int64(0)}),
+		},
+	})
+}
+
+func TestDecodeRLEHybrid(t *testing.T) {
+	// the bit packed example of the parquet encodings spec
+	vals, err := decodeRLEHybrid([]byte{0x03, 0x88, 0xc6, 0xfa}, 3, 8)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, vals, []int{0, 1, 2, 3, 4, 5, 6, 7})
+
+	// a run of 4 and a partial bit packed group
+	vals, err = decodeRLEHybrid([]byte{0x08, 0x05, 0x03, 0x0b, 0x00, 0x00, 0x00}, 4, 6)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, vals, []int{5, 5, 5, 5, 11, 0})
+
+	_, err = decodeRLEHybrid([]byte{0x03, 0x88}, 3, 8)
+	assert.Equal(t, err != nil, true)
+}
+
+func TestParquetInventorySearch(t *testing.T) {
+	file := testParquetInventory()
+	m := common.NewDefaultRegexMatcher()
+
+	matches, err := SearchColumnarInventoryFile(bytes.NewReader(file), int64(len(file)), InventoryFormatParquet, nil, `\.parquet$`, m)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 2)
+	assert.Equal(t, matches[0].Bucket, "datalake")
+	assert.Equal(t, matches[0].Fields, map[string]string{
+		"Bucket":           "datalake",
+		"Key":              "events/a.parquet",
+		"Size":             "1024",
+		"LastModifiedDate": "2023-01-01T00:00:00.000Z",
+		"StorageClass":     "STANDARD",
+	})
+	// null size is left out
+	_, exist := matches[1].Fields["Size"]
+	assert.Equal(t, exist, false)
+	assert.Equal(t, matches[1].Key, "events/b c.parquet")
+
+	matches, err = SearchColumnarInventoryFile(bytes.NewReader(file), int64(len(file)), InventoryFormatParquet, []string{"Key", "StorageClass"}, `^GLACIER$`, m)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 1)
+	assert.Equal(t, matches[0].Column, "StorageClass")
+	assert.Equal(t, matches[0].Fields["LastModifiedDate"], "2023-01-02T00:00:00.000Z")
+
+	_, err = SearchColumnarInventoryFile(bytes.NewReader(file[:20]), 20, InventoryFormatParquet, nil, `.`, m)
+	assert.Equal(t, err != nil, true)
+}
@@ -1,8 +1,11 @@
 package s3search
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"sort"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/aws-sdk-go/aws"
@@ -15,9 +18,13 @@ import (
 const TooManyBucketsErr string = "TooManyBucketsErr"
 
 type _s3AsyncRes struct {
-	Bucket string
-	Keys   []string
-	Err    error
+	Bucket          string
+	Region          string
+	Objects         []*ObjectMatch
+	ContentMatches  []*ContentMatch
+	MetadataMatches []*MetadataMatch
+	VersionMatches  []*VersionMatch
+	Err             error
 }
 
 type Input struct {
@@ -29,8 +36,26 @@ type Input struct {
 	Value string
 	// prefix for keys to start from
 	Prefix string
-	// TODO: implement search keys content
+	// if true Value is matched against the objects content instead of the key names
 	SearchKeysContent bool
+	// if true Value is matched against objects user metadata, content type and tags instead of the key names
+	SearchMetadata bool
+	// if true Value is matched against the keys of all object versions and delete markers
+	SearchVersions bool
+	// levels of common prefixes (delimiter /) to split a bucket into shards listed concurrently, 0 lists sequentially
+	ShardDepth int
+	// stop listing all buckets once a key matched
+	StopOnFirstMatch bool
+	// filter objects by size, age and storage class (optional)
+	ObjectFilter *ObjectFilter
+	// when searching buckets configuration, which configurations to fetch, all if empty
+	BucketConfigs []awsu.BucketConfigType
+	// when searching metadata, tags and limits
+	MetadataOptions *MetadataOptions
+	// when searching content or metadata, only keys matching this pattern are read (optional)
+	KeyPattern string
+	// when searching content, which objects are downloaded
+	ContentFilter *ContentFilter
 	// if BucketNamePattern is provided this is ignored
 	// max number to allow listing all buckets when no BucketNamePattern is provided
 	// if BucketNamePattern is not provided, the searcher will still allow searching if total buckets number is
@@ -50,15 +75,63 @@ func NewSearchInput(bucketNamePattern, prefix, value string, parallel int, allow
 		Parallel:             parallel,
 		MaxAllowedAllBuckets: parallel,
 		AllowAllBucket:       allowAllBuckets,
+		ShardDepth:           1,
 	}
 }
 
+func (i *Input) WithObjectFilter(filter *ObjectFilter) *Input {
+	i.ObjectFilter = filter
+	return i
+}
+
+func (i *Input) WithShardDepth(depth int) *Input {
+	i.ShardDepth = depth
+	return i
+}
+
+func (i *Input) WithStopOnFirstMatch() *Input {
+	i.StopOnFirstMatch = true
+	return i
+}
+
+func (i *Input) WithContentSearch(keyPattern string, filter *ContentFilter) *Input {
+	i.SearchKeysContent = true
+	i.KeyPattern = keyPattern
+	i.ContentFilter = filter
+	return i
+}
+
+func (i *Input) WithMetadataSearch(keyPattern string, opts *MetadataOptions) *Input {
+	i.SearchMetadata = true
+	i.KeyPattern = keyPattern
+	i.MetadataOptions = opts
+	return i
+}
+
+func (i *Input) WithVersionsSearch() *Input {
+	i.SearchVersions = true
+	return i
+}
+
 type Output struct {
 	BucketToMatches map[string][]string
+	// bucket to matched objects with size, last modified and storage class
+	BucketToObjects map[string][]*ObjectMatch
+	// bucket to matched lines when searching content
+	BucketToContentMatches map[string][]*ContentMatch
+	// bucket to matched attributes when searching metadata
+	BucketToMetadataMatches map[string][]*MetadataMatch
+	// bucket to matched versions and delete markers when searching versions
+	BucketToVersionMatches map[string][]*VersionMatch
+	// bucket to matched configuration documents when searching buckets
+	BucketToConfigMatches map[string][]*BucketConfigMatch
+	// bucket to the region it lives in, empty if it could not be detected
+	BucketToRegion map[string]string
 }
 
 type Searcher[C awsu.S3API, M common.Matcher] interface {
 	Search(i *Input) (*Output, error)
+	SearchBuckets(i *Input) (*Output, error)
 }
 
 type DefaultSearcher[C awsu.S3API, M common.Matcher] struct {
@@ -74,30 +147,18 @@ func NewSearcher[C awsu.S3API, Comp common.Matcher](c awsu.S3API, m common.Match
 }
 
 func (s *DefaultSearcher[CC, Matcher]) Search(i *Input) (*Output, error) {
-	allBuckets, err := s.Client.ListAllBuckets()
-	var targetBuckets []types.Bucket
 	filteredResult := &Output{
-		BucketToMatches: map[string][]string{},
+		BucketToMatches:         map[string][]string{},
+		BucketToObjects:         map[string][]*ObjectMatch{},
+		BucketToContentMatches:  map[string][]*ContentMatch{},
+		BucketToMetadataMatches: map[string][]*MetadataMatch{},
+		BucketToVersionMatches:  map[string][]*VersionMatch{},
+		BucketToConfigMatches:   map[string][]*BucketConfigMatch{},
+		BucketToRegion:          map[string]string{},
 	}
+	targetBuckets, err := s.selectBuckets(i)
 	if err != nil {
-		return nil, fmt.Errorf("searcher failed listing buckets %s", err.Error())
-	}
-
-	if i.BucketNamePattern != "" {
-		for _, b := range allBuckets {
-			match, err := s.Comparator.IsMatch(i.BucketNamePattern, aws.StringValue(b.Name))
-			if err != nil {
-				return nil, fmt.Errorf("failed matching bucket name in comparator %s", err.Error())
-			}
-			if match {
-				targetBuckets = append(targetBuckets, b)
-			}
-		}
-	} else if i.AllowAllBucket || len(allBuckets) <= i.MaxAllowedAllBuckets {
-		log.Warningf("going to search in all buckets %d might impact performance", len(allBuckets))
-		targetBuckets = allBuckets
-	} else {
-		return nil, fmt.Errorf(TooManyBucketsErr)
+		return nil, err
 	}
 	log.WithField("buckets_number", len(targetBuckets)).Info("searching in buckets")
 
@@ -106,46 +167,188 @@ func (s *DefaultSearcher[CC, Matcher]) Search(i *Input) (*Output, error) {
 	pool := workPool.NewWorkerPool(int(workersNum))
 
 	asyncResults := make(chan *_s3AsyncRes, len(targetBuckets))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	for _, b := range targetBuckets {
 		bucketName := aws.StringValue(b.Name)
 		pool.Submit(func() {
-			keys, err := s.Client.ListAllObjects(bucketName, i.Prefix)
 			res := &_s3AsyncRes{
 				Bucket: bucketName,
 			}
+			if ctx.Err() != nil {
+				asyncResults <- res
+				return
+			}
+			region, err := s.Client.GetBucketRegion(bucketName)
+			if err != nil {
+				log.WithError(err).WithField("bucket", bucketName).Debug("failed detecting bucket region")
+			}
+			res.Region = region
+			if !i.SearchKeysContent && !i.SearchMetadata && !i.SearchVersions {
+				res.Objects, res.Err = s.searchKeysSharded(ctx, cancel, bucketName, i)
+				asyncResults <- res
+				return
+			}
+			if i.SearchVersions {
+				versions, deleteMarkers, err := s.Client.ListAllObjectVersions(bucketName, i.Prefix)
+				if err == nil {
+					res.VersionMatches, err = MatchObjectVersions(versions, deleteMarkers, i.Value, s.Comparator)
+				}
+				res.Err = err
+				asyncResults <- res
+				return
+			}
+			keys, err := s.Client.ListAllObjects(bucketName, i.Prefix)
 			if err != nil {
 				res.Err = err
+			} else if i.SearchKeysContent {
+				res.ContentMatches, res.Err = s.searchObjectsContent(bucketName, keys, i)
 			} else {
-				for _, k := range keys {
-					if isMatch, err := s.Comparator.IsMatch(i.Value, aws.StringValue(k.Key)); isMatch {
-						if err != nil {
-							log.WithError(err).WithField("key", aws.StringValue(k.Key)).Error("failed pattern matching key probablly bug")
-							continue
-						}
-						res.Keys = append(res.Keys, aws.StringValue(k.Key))
-					}
-				}
+				res.MetadataMatches, res.Err = s.searchObjectsMetadata(bucketName, keys, i)
 			}
 			asyncResults <- res
 		})
 	}
 
 	pool.RunAll()
-	size := len(targetBuckets)
-	counter := 1
+	close(asyncResults)
 	for r := range asyncResults {
-		counter++
-
+		if r.Region != "" {
+			filteredResult.BucketToRegion[r.Bucket] = r.Region
+		}
 		if r.Err != nil {
-			log.WithError(r.Err).WithField("bucket", r.Bucket).Error("failed searching keys in bucket (potential fix: sure the target bucket is in the target region)")
+			log.WithError(r.Err).WithFields(log.Fields{"bucket": r.Bucket, "region": r.Region}).Error("failed searching keys in bucket")
+		} else if i.SearchKeysContent {
+			filteredResult.BucketToContentMatches[r.Bucket] = r.ContentMatches
+		} else if i.SearchMetadata {
+			filteredResult.BucketToMetadataMatches[r.Bucket] = r.MetadataMatches
+		} else if i.SearchVersions {
+			filteredResult.BucketToVersionMatches[r.Bucket] = r.VersionMatches
 		} else {
-			filteredResult.BucketToMatches[r.Bucket] = r.Keys
+			filteredResult.BucketToObjects[r.Bucket] = r.Objects
+			keys := []string{}
+			for _, o := range r.Objects {
+				keys = append(keys, o.Key)
+			}
+			filteredResult.BucketToMatches[r.Bucket] = keys
 		}
+	}
+	return filteredResult, nil
+}
 
-		if counter >= size {
+// selectBuckets lists all buckets and filters them by the bucket name pattern
+func (s *DefaultSearcher[CC, Matcher]) selectBuckets(i *Input) ([]types.Bucket, error) {
+	allBuckets, err := s.Client.ListAllBuckets()
+	if err != nil {
+		return nil, fmt.Errorf("searcher failed listing buckets %s", err.Error())
+	}
+	var targetBuckets []types.Bucket
+	if i.BucketNamePattern != "" {
+		for _, b := range allBuckets {
+			match, err := s.Comparator.IsMatch(i.BucketNamePattern, aws.StringValue(b.Name))
+			if err != nil {
+				return nil, fmt.Errorf("failed matching bucket name in comparator %s", err.Error())
+			}
+			if match {
+				targetBuckets = append(targetBuckets, b)
+			}
+		}
+	} else if i.AllowAllBucket || len(allBuckets) <= i.MaxAllowedAllBuckets {
+		log.Warningf("going to search in all buckets %d might impact performance", len(allBuckets))
+		targetBuckets = allBuckets
+	} else {
+		return nil, fmt.Errorf(TooManyBucketsErr)
+	}
+	return targetBuckets, nil
+}
+
+// selectCandidates returns the objects allowed and matching the key pattern (if set) up to limit (0 is unlimited)
+func (s *DefaultSearcher[CC, Matcher]) selectCandidates(objects []types.Object, i *Input, allowed func(types.Object) bool, limit int) ([]types.Object, error) {
+	var candidates []types.Object
+	for _, o := range objects {
+		if limit > 0 && len(candidates) >= limit {
 			break
 		}
+		if allowed != nil && !allowed(o) {
+			continue
+		}
+		if !i.ObjectFilter.IsObjectAllowed(o) {
+			continue
+		}
+		if i.KeyPattern != "" {
+			match, err := s.Comparator.IsMatch(i.KeyPattern, aws.StringValue(o.Key))
+			if err != nil {
+				return nil, fmt.Errorf("failed matching key pattern %s", err.Error())
+			}
+			if !match {
+				continue
+			}
+		}
+		candidates = append(candidates, o)
 	}
-	return filteredResult, nil
+	return candidates, nil
+}
+
+func (s *DefaultSearcher[CC, Matcher]) searchObjectsContent(bucket string, objects []types.Object, i *Input) ([]*ContentMatch, error) {
+	filter := i.ContentFilter
+	if filter == nil {
+		filter = &ContentFilter{}
+	}
+	candidates, err := s.selectCandidates(objects, i, filter.IsObjectAllowed, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"bucket":     bucket,
+		"candidates": len(candidates),
+		"total_keys": len(objects),
+	}).Debug("searching objects content")
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	var mu sync.Mutex
+	var matches []*ContentMatch
+	workersNum := math.Min(float64(len(candidates)), float64(i.Parallel))
+	pool := workPool.NewWorkerPool(int(workersNum))
+	for _, o := range candidates {
+		key := aws.StringValue(o.Key)
+		pool.Submit(func() {
+			found, err := s.searchObjectContent(bucket, key, i, filter)
+			if err != nil {
+				// matches found before a read failure or the size limit are still reported
+				log.WithError(err).WithField("key", key).Warn("failed searching object content")
+			}
+			mu.Lock()
+			matches = append(matches, found...)
+			mu.Unlock()
+		})
+	}
+	pool.RunAll()
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].Key != matches[b].Key {
+			return matches[a].Key < matches[b].Key
+		}
+		return matches[a].Line < matches[b].Line
+	})
+	return matches, nil
+}
+
+func (s *DefaultSearcher[CC, Matcher]) searchObjectContent(bucket, key string, i *Input, filter *ContentFilter) ([]*ContentMatch, error) {
+	obj, err := s.Client.GetObject(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+
+	if !filter.IsContentTypeAllowed(aws.StringValue(obj.ContentType)) {
+		log.WithFields(log.Fields{"key": key, "content_type": aws.StringValue(obj.ContentType)}).Trace("skipping content type")
+		return nil, nil
+	}
+
+	return SearchContent(obj.Body, key, i.Value, s.Comparator, filter.MaxObjectSize)
 }
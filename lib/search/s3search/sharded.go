@@ -0,0 +1,128 @@
+package s3search
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	workPool "github.com/isan-rivkin/surf/lib/common"
+	log "github.com/sirupsen/logrus"
+)
+
+const shardDelimiter = "/"
+
+// discoverShards walks depth levels of common prefixes under prefix with a delimiter.
+// objects found on the way (not under any deeper prefix) are passed to onObjects,
+// the returned prefixes are the shards that still need to be listed in full
+func (s *DefaultSearcher[CC, Matcher]) discoverShards(ctx context.Context, bucket, prefix string, depth int, onObjects func([]types.Object) bool) ([]string, error) {
+	shards := []string{prefix}
+	for level := 0; level < depth; level++ {
+		var next []string
+		for _, p := range shards {
+			err := s.Client.ListObjectsPages(ctx, bucket, p, shardDelimiter, func(page *s3.ListObjectsV2Output) bool {
+				if !onObjects(page.Contents) {
+					return false
+				}
+				for _, cp := range page.CommonPrefixes {
+					next = append(next, aws.StringValue(cp.Prefix))
+				}
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+			if ctx.Err() != nil {
+				return nil, nil
+			}
+		}
+		shards = next
+		if len(shards) == 0 {
+			break
+		}
+	}
+	return shards, nil
+}
+
+// searchKeysSharded lists prefix shards of the bucket concurrently and matches keys page by page
+// if StopOnFirstMatch is set cancel is called on the first match and all listings stop
+func (s *DefaultSearcher[CC, Matcher]) searchKeysSharded(ctx context.Context, cancel context.CancelFunc, bucket string, i *Input) ([]*ObjectMatch, error) {
+	var mu sync.Mutex
+	var matches []*ObjectMatch
+	var matchErr error
+
+	onObjects := func(objects []types.Object) bool {
+		for _, o := range objects {
+			if !i.ObjectFilter.IsObjectAllowed(o) {
+				continue
+			}
+			key := aws.StringValue(o.Key)
+			match, err := s.Comparator.IsMatch(i.Value, key)
+			if err != nil {
+				mu.Lock()
+				matchErr = fmt.Errorf("failed matching key %s %s", key, err.Error())
+				mu.Unlock()
+				cancel()
+				return false
+			}
+			if !match {
+				continue
+			}
+			mu.Lock()
+			matches = append(matches, NewObjectMatch(o))
+			mu.Unlock()
+			if i.StopOnFirstMatch {
+				cancel()
+				return false
+			}
+		}
+		return ctx.Err() == nil
+	}
+
+	shards, err := s.discoverShards(ctx, bucket, i.Prefix, i.ShardDepth, onObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"bucket": bucket,
+		"shards": len(shards),
+	}).Debug("listing bucket shards")
+
+	if len(shards) > 0 {
+		var errs []error
+		workersNum := math.Min(float64(len(shards)), float64(i.Parallel))
+		pool := workPool.NewWorkerPool(int(workersNum))
+		for _, shard := range shards {
+			shard := shard
+			pool.Submit(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				if err := s.Client.ListObjectsPages(ctx, bucket, shard, "", func(page *s3.ListObjectsV2Output) bool {
+					return onObjects(page.Contents)
+				}); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			})
+		}
+		pool.RunAll()
+		if len(errs) > 0 {
+			return matches, errs[0]
+		}
+	}
+
+	if matchErr != nil {
+		return nil, matchErr
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].Key < matches[b].Key
+	})
+	return matches, nil
+}
@@ -0,0 +1,81 @@
+package s3search_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/isan-rivkin/surf/lib/awsu"
+	common "github.com/isan-rivkin/surf/lib/search"
+	search "github.com/isan-rivkin/surf/lib/search/s3search"
+	"github.com/magiconair/properties/assert"
+)
+
+// fakeS3 serves a single page per listing from in memory keys
+type fakeS3 struct {
+	awsu.S3API
+	keys     []string
+	mu       sync.Mutex
+	listings []string
+}
+
+func (f *fakeS3) ListAllBuckets() ([]types.Bucket, error) {
+	return []types.Bucket{{Name: aws.String("bucket")}}, nil
+}
+
+func (f *fakeS3) GetBucketRegion(bucket string) (string, error) {
+	return "us-east-1", nil
+}
+
+func (f *fakeS3) ListObjectsPages(ctx context.Context, bucket, prefix, delimiter string, fn func(page *s3.ListObjectsV2Output) bool) error {
+	f.mu.Lock()
+	f.listings = append(f.listings, prefix+"|"+delimiter)
+	f.mu.Unlock()
+
+	page := &s3.ListObjectsV2Output{}
+	seen := map[string]bool{}
+	for _, k := range f.keys {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(k, prefix)
+		if idx := strings.Index(rest, delimiter); delimiter != "" && idx != -1 {
+			cp := prefix + rest[:idx+1]
+			if !seen[cp] {
+				seen[cp] = true
+				page.CommonPrefixes = append(page.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(cp)})
+			}
+			continue
+		}
+		page.Contents = append(page.Contents, types.Object{Key: aws.String(k)})
+	}
+	fn(page)
+	return nil
+}
+
+func TestShardedKeySearch(t *testing.T) {
+	client := &fakeS3{keys: []string{"root.json", "a/1.json", "a/2.txt", "b/c/3.json", "d/4.txt"}}
+	s := search.NewSearcher[awsu.S3API, common.Matcher](client, common.NewDefaultRegexMatcher())
+
+	out, err := s.Search(search.NewSearchInput("bucket", "", `\.json$`, 5, false).WithShardDepth(2))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, out.BucketToMatches["bucket"], []string{"a/1.json", "b/c/3.json", "root.json"})
+
+	sort.Strings(client.listings)
+	// 1 root delimiter listing, 3 second level delimiter listings and 1 full listing of the b/c/ shard
+	assert.Equal(t, client.listings, []string{"a/|/", "b/c/|", "b/|/", "d/|/", "|/"})
+}
+
+func TestShardedKeySearchStopOnFirstMatch(t *testing.T) {
+	client := &fakeS3{keys: []string{"a/1.json", "a/2.json", "a/3.json"}}
+	s := search.NewSearcher[awsu.S3API, common.Matcher](client, common.NewDefaultRegexMatcher())
+
+	out, err := s.Search(search.NewSearchInput("bucket", "", `\.json$`, 5, false).WithStopOnFirstMatch())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(out.BucketToMatches["bucket"]), 1)
+}
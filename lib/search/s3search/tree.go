@@ -0,0 +1,110 @@
+package s3search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const keyDelimiter = "/"
+
+// KeyTreeNode groups matched keys by prefix, Count is the number of matched keys under the node
+type KeyTreeNode struct {
+	Name     string
+	Count    int
+	IsKey    bool
+	children map[string]*KeyTreeNode
+}
+
+func NewKeyTree(keys []string) *KeyTreeNode {
+	root := &KeyTreeNode{children: map[string]*KeyTreeNode{}}
+	for _, k := range keys {
+		root.Count++
+		node := root
+		parts := strings.Split(k, keyDelimiter)
+		for idx, p := range parts {
+			// keys ending with the delimiter are "folder" placeholders
+			if p == "" {
+				continue
+			}
+			child, exist := node.children[p]
+			if !exist {
+				child = &KeyTreeNode{Name: p, children: map[string]*KeyTreeNode{}}
+				node.children[p] = child
+			}
+			child.Count++
+			if idx == len(parts)-1 {
+				child.IsKey = true
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+// Children sorted by name
+func (n *KeyTreeNode) Children() []*KeyTreeNode {
+	var children []*KeyTreeNode
+	for _, c := range n.children {
+		children = append(children, c)
+	}
+	sort.Slice(children, func(a, b int) bool {
+		return children[a].Name < children[b].Name
+	})
+	return children
+}
+
+func (n *KeyTreeNode) isDir() bool {
+	return len(n.children) > 0
+}
+
+// Render draws the tree like `tree`, directories deeper than maxDepth are collapsed with their match count, 0 is unlimited
+func (n *KeyTreeNode) Render(maxDepth int) []string {
+	var lines []string
+	n.render("", 1, maxDepth, &lines)
+	return lines
+}
+
+func (n *KeyTreeNode) render(indent string, depth, maxDepth int, lines *[]string) {
+	children := n.Children()
+	for idx, c := range children {
+		branch, nextIndent := "├── ", indent+"│   "
+		if idx == len(children)-1 {
+			branch, nextIndent = "└── ", indent+"    "
+		}
+		if !c.isDir() {
+			*lines = append(*lines, indent+branch+c.Name)
+			continue
+		}
+		// a key that is also a prefix of other keys is shown as a sibling leaf before its directory
+		if c.IsKey {
+			*lines = append(*lines, indent+"├── "+c.Name)
+		}
+		*lines = append(*lines, fmt.Sprintf("%s%s%s%s (%d)", indent, branch, c.Name, keyDelimiter, c.Count))
+		if maxDepth > 0 && depth >= maxDepth {
+			continue
+		}
+		c.render(nextIndent, depth+1, maxDepth, lines)
+	}
+}
+
+// PrefixCounts counts matched keys per prefix of up to depth levels, keys with less levels are counted under their own prefix
+func PrefixCounts(keys []string, depth int) map[string]int {
+	if depth <= 0 {
+		depth = 1
+	}
+	counts := map[string]int{}
+	for _, k := range keys {
+		parts := strings.Split(k, keyDelimiter)
+		prefix := ""
+		if len(parts) > 1 {
+			levels := depth
+			if levels > len(parts)-1 {
+				levels = len(parts) - 1
+			}
+			prefix = strings.Join(parts[:levels], keyDelimiter) + keyDelimiter
+		}
+		counts[prefix]++
+	}
+	return counts
+}
@@ -0,0 +1,49 @@
+package s3search_test
+
+import (
+	"testing"
+
+	search "github.com/isan-rivkin/surf/lib/search/s3search"
+	"github.com/magiconair/properties/assert"
+)
+
+var testTreeKeys = []string{
+	"logs/2023/01/a.log",
+	"logs/2023/01/b.log",
+	"logs/2023/02/c.log",
+	"configs/app.yaml",
+	"root.txt",
+}
+
+func TestKeyTreeRender(t *testing.T) {
+	tree := search.NewKeyTree(testTreeKeys)
+	assert.Equal(t, tree.Count, 5)
+	assert.Equal(t, tree.Render(2), []string{
+		"├── configs/ (1)",
+		"│   └── app.yaml",
+		"├── logs/ (3)",
+		"│   └── 2023/ (3)",
+		"└── root.txt",
+	})
+	assert.Equal(t, len(tree.Render(0)), 10)
+}
+
+func TestKeyTreeRenderKeyAndDir(t *testing.T) {
+	// "backup" is both a key and the prefix of other keys, and the last child
+	tree := search.NewKeyTree([]string{"a.txt", "backup", "backup/1.tar"})
+	assert.Equal(t, tree.Render(0), []string{
+		"├── a.txt",
+		"├── backup",
+		"└── backup/ (2)",
+		"    └── 1.tar",
+	})
+}
+
+func TestPrefixCounts(t *testing.T) {
+	counts := search.PrefixCounts(testTreeKeys, 2)
+	assert.Equal(t, counts, map[string]int{
+		"logs/2023/": 3,
+		"configs/":   1,
+		"":           1,
+	})
+}
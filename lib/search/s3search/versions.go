@@ -0,0 +1,79 @@
+package s3search
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	common "github.com/isan-rivkin/surf/lib/search"
+)
+
+type VersionMatch struct {
+	Key          string
+	VersionID    string
+	LastModified time.Time
+	Size         int64
+	// owner display name or canonical id, for delete markers it is who deleted the key
+	Owner          string
+	IsLatest       bool
+	IsDeleteMarker bool
+}
+
+func ownerName(o *types.Owner) string {
+	if o == nil {
+		return ""
+	}
+	if name := aws.StringValue(o.DisplayName); name != "" {
+		return name
+	}
+	return aws.StringValue(o.ID)
+}
+
+// MatchObjectVersions matches the value against the keys of all versions and delete markers
+// results are grouped by key, newest version first
+func MatchObjectVersions(versions []types.ObjectVersion, deleteMarkers []types.DeleteMarkerEntry, value string, m common.Matcher) ([]*VersionMatch, error) {
+	var matches []*VersionMatch
+	for _, v := range versions {
+		key := aws.StringValue(v.Key)
+		match, err := m.IsMatch(value, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed matching object version key %s", err.Error())
+		}
+		if match {
+			matches = append(matches, &VersionMatch{
+				Key:          key,
+				VersionID:    aws.StringValue(v.VersionId),
+				LastModified: aws.TimeValue(v.LastModified),
+				Size:         v.Size,
+				Owner:        ownerName(v.Owner),
+				IsLatest:     v.IsLatest,
+			})
+		}
+	}
+	for _, d := range deleteMarkers {
+		key := aws.StringValue(d.Key)
+		match, err := m.IsMatch(value, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed matching delete marker key %s", err.Error())
+		}
+		if match {
+			matches = append(matches, &VersionMatch{
+				Key:            key,
+				VersionID:      aws.StringValue(d.VersionId),
+				LastModified:   aws.TimeValue(d.LastModified),
+				Owner:          ownerName(d.Owner),
+				IsLatest:       d.IsLatest,
+				IsDeleteMarker: true,
+			})
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].Key != matches[b].Key {
+			return matches[a].Key < matches[b].Key
+		}
+		return matches[a].LastModified.After(matches[b].LastModified)
+	})
+	return matches, nil
+}
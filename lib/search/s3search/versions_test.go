@@ -0,0 +1,34 @@
+package s3search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go/aws"
+	common "github.com/isan-rivkin/surf/lib/search"
+	search "github.com/isan-rivkin/surf/lib/search/s3search"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestMatchObjectVersions(t *testing.T) {
+	now := time.Now()
+	versions := []types.ObjectVersion{
+		{Key: aws.String("configs/prod/app.yaml"), VersionId: aws.String("v1"), LastModified: aws.Time(now.Add(-2 * time.Hour)), Size: 10},
+		{Key: aws.String("configs/prod/db.yaml"), VersionId: aws.String("v2"), LastModified: aws.Time(now), IsLatest: true},
+	}
+	deleteMarkers := []types.DeleteMarkerEntry{
+		{Key: aws.String("configs/prod/app.yaml"), VersionId: aws.String("v3"), LastModified: aws.Time(now), IsLatest: true, Owner: &types.Owner{DisplayName: aws.String("ops")}},
+	}
+
+	matches, err := search.MatchObjectVersions(versions, deleteMarkers, `app\.yaml$`, common.NewDefaultRegexMatcher())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(matches), 2)
+	// newest first, the delete marker is the latest version
+	assert.Equal(t, matches[0].VersionID, "v3")
+	assert.Equal(t, matches[0].IsDeleteMarker, true)
+	assert.Equal(t, matches[0].IsLatest, true)
+	assert.Equal(t, matches[0].Owner, "ops")
+	assert.Equal(t, matches[1].VersionID, "v1")
+	assert.Equal(t, matches[1].Size, int64(10))
+}
@@ -64,3 +64,22 @@ func SanitizeASCII(s string) string {
 	rg := regexp.MustCompile(`\s+`)
 	return rg.ReplaceAllString(cleanVal, " ")
 }
+
+// Highlight colors the [start, end) offsets of s, transform (i.e SanitizeASCII) is applied to every piece so offsets of the original text stay valid
+func Highlight(s string, offsets [][]int, transform func(string) string) string {
+	if transform == nil {
+		transform = func(txt string) string { return txt }
+	}
+	var b strings.Builder
+	last := 0
+	for _, o := range offsets {
+		if len(o) != 2 || o[0] < last || o[1] > len(s) || o[0] >= o[1] {
+			continue
+		}
+		b.WriteString(transform(s[last:o[0]]))
+		b.WriteString(ColorHiYellow(transform(s[o[0]:o[1]])))
+		last = o[1]
+	}
+	b.WriteString(transform(s[last:]))
+	return b.String()
+}